@@ -0,0 +1,240 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+
+	spb ".../proto/stratus_proto"
+)
+
+// AnalysisKind is the kind of an analysis finding.
+type AnalysisKind string
+
+const (
+	// Shadowed entries never match, an earlier entry with the other action
+	// matches all their traffic.
+	Shadowed AnalysisKind = "shadowed"
+	// Redundant entries can be removed without changing what the access
+	// list permits, an earlier or later entry with the same action matches
+	// all their traffic.
+	Redundant AnalysisKind = "redundant"
+	// Overlap entries share some but not all traffic with an earlier entry
+	// with the other action, so their order matters.
+	Overlap AnalysisKind = "overlap"
+)
+
+// AnalysisFinding is an entry of an access list found dead or overlapping
+// by comparison with another entry.
+type AnalysisFinding struct {
+	Kind  AnalysisKind
+	Entry *Entry
+	By    *Entry
+}
+
+func (f *AnalysisFinding) String() string {
+	return fmt.Sprintf("line %d: %s by line %d: %s", f.Entry.Line, f.Kind, f.By.Line, f.Entry.Text)
+}
+
+// ACLReport is the analysis of an access list.
+type ACLReport struct {
+	Name      string
+	IPVersion int32
+	Findings  []*AnalysisFinding
+}
+
+// Dead returns the number of shadowed and redundant entries.
+func (r *ACLReport) Dead() int {
+	n := 0
+	for _, f := range r.Findings {
+		if f.Kind != Overlap {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *ACLReport) String() string {
+	b := bytes.NewBuffer([]byte{})
+	fmt.Fprintf(b, "acl %s (ipv%d): %d dead entries, %d overlaps\n",
+		r.Name, r.IPVersion, r.Dead(), len(r.Findings)-r.Dead())
+	for _, f := range r.Findings {
+		fmt.Fprintf(b, "  %s\n", f)
+	}
+	return b.String()
+}
+
+// protocolNumbers maps protocol keywords to their numbers, which entries
+// may use instead, by ip version. icmp is ICMPv6 in IPv6 access lists.
+var protocolNumbers = map[int32]map[string]string{
+	4: {
+		"ahp": "51", "eigrp": "88", "esp": "50", "gre": "47", "icmp": "1", "igmp": "2", "ipinip": "4",
+		"ospf": "89", "pim": "103", "sctp": "132", "tcp": "6", "udp": "17",
+	},
+	6: {
+		"ahp": "51", "esp": "50", "icmp": "58", "pcp": "108", "sctp": "132", "tcp": "6", "udp": "17",
+	},
+}
+
+// loggingOptions are the options of entries which do not change what they
+// match.
+var loggingOptions = map[string]bool{"log": true, "log-input": true}
+
+// AnalyzeACL compares every permit and deny entry of the access list with
+// the earlier and later entries. Entries which cannot be parsed are left
+// out.
+func AnalyzeACL(a *ParsedACL) *ACLReport {
+	r := &ACLReport{Name: a.Name, IPVersion: a.IPVersion}
+	var rules []*Entry
+	for _, e := range a.Entries {
+		if e.Err == nil && e.Action != "" {
+			rules = append(rules, e)
+		}
+	}
+	for j, e := range rules {
+		var finding *AnalysisFinding
+		for _, prev := range rules[:j] {
+			if contains(prev, e, a.IPVersion) {
+				kind := Redundant
+				if prev.Action != e.Action {
+					kind = Shadowed
+				}
+				finding = &AnalysisFinding{kind, e, prev}
+				break
+			}
+		}
+		if finding == nil {
+			finding = redundantByLater(rules, j, a.IPVersion)
+		}
+		if finding == nil {
+			for _, prev := range rules[:j] {
+				if prev.Action != e.Action && overlaps(prev, e, a.IPVersion) && !contains(e, prev, a.IPVersion) {
+					finding = &AnalysisFinding{Overlap, e, prev}
+					break
+				}
+			}
+		}
+		if finding != nil {
+			r.Findings = append(r.Findings, finding)
+		}
+	}
+	return r
+}
+
+// redundantByLater returns a finding if a later entry with the same action
+// matches all traffic of entry j, and no entry between them with the other
+// action matches any of it.
+func redundantByLater(rules []*Entry, j int, ipVer int32) *AnalysisFinding {
+	e := rules[j]
+	for _, next := range rules[j+1:] {
+		if next.Action != e.Action {
+			if overlaps(next, e, ipVer) {
+				return nil
+			}
+			continue
+		}
+		if contains(next, e, ipVer) {
+			return &AnalysisFinding{Redundant, e, next}
+		}
+	}
+	return nil
+}
+
+// anyProtocol reports whether the protocol of the entry matches all traffic
+// of the ip version.
+func anyProtocol(e *Entry, ipVer int32) bool {
+	return e.Protocol == "ip" && ipVer == 4 || e.Protocol == "ipv6" && ipVer == 6
+}
+
+// protocol returns the protocol number of the entry of an access list of the
+// ip version, or its keyword.
+func protocol(e *Entry, ipVer int32) string {
+	if n, ok := protocolNumbers[ipVer][e.Protocol]; ok {
+		return n
+	}
+	return e.Protocol
+}
+
+// filterOptions returns the options of the entry which narrow its match.
+func filterOptions(e *Entry) []string {
+	var opts []string
+	for _, o := range e.Options {
+		if !loggingOptions[o] {
+			opts = append(opts, o)
+		}
+	}
+	return opts
+}
+
+// contains reports whether entry a matches all traffic of entry b.
+func contains(a, b *Entry, ipVer int32) bool {
+	if !anyProtocol(a, ipVer) && protocol(a, ipVer) != protocol(b, ipVer) {
+		return false
+	}
+	if !a.Src.Contains(b.Src) || !a.Dst.Contains(b.Dst) {
+		return false
+	}
+	if !anyProtocol(a, ipVer) && (!portsContain(a.SrcPort, b.SrcPort) || !portsContain(a.DstPort, b.DstPort)) {
+		return false
+	}
+	aOpts, bOpts := filterOptions(a), filterOptions(b)
+	if len(aOpts) == 0 {
+		return true
+	}
+	if anyProtocol(a, ipVer) && !anyProtocol(b, ipVer) {
+		return false
+	}
+	return reflect.DeepEqual(aOpts, bOpts)
+}
+
+// overlaps reports whether entries a and b may match the same traffic.
+func overlaps(a, b *Entry, ipVer int32) bool {
+	if !anyProtocol(a, ipVer) && !anyProtocol(b, ipVer) && protocol(a, ipVer) != protocol(b, ipVer) {
+		return false
+	}
+	if !a.Src.Overlaps(b.Src) || !a.Dst.Overlaps(b.Dst) {
+		return false
+	}
+	return portsOverlap(a.SrcPort, b.SrcPort) && portsOverlap(a.DstPort, b.DstPort)
+}
+
+// portsContain reports whether port match a matches all ports of b. Port
+// matches without a range only contain equal ones.
+func portsContain(a, b *PortMatch) bool {
+	aLo, aHi, aOK := a.Range()
+	bLo, bHi, bOK := b.Range()
+	if !aOK || !bOK {
+		return reflect.DeepEqual(a, b)
+	}
+	return aLo <= bLo && bHi <= aHi
+}
+
+// portsOverlap reports whether port matches a and b may match a port in
+// common.
+func portsOverlap(a, b *PortMatch) bool {
+	aLo, aHi, aOK := a.Range()
+	bLo, bHi, bOK := b.Range()
+	if !aOK || !bOK {
+		return true
+	}
+	return aLo <= bHi && bLo <= aHi
+}
+
+// AnalyzePush analyzes the access lists of the push items of vendors with
+// IOS/XE syntax, once for every distinct acl data.
+func AnalyzePush(pushPb []*spb.ACLPushItem) []*ACLReport {
+	var reports []*ACLReport
+	seen := make(map[string]bool)
+	for _, item := range pushPb {
+		if !lintVendors[item.GetVendor()] || seen[item.GetData()] {
+			continue
+		}
+		seen[item.GetData()] = true
+		for _, a := range ParseACLs(item.GetData()) {
+			if a.Extended {
+				reports = append(reports, AnalyzeACL(a))
+			}
+		}
+	}
+	return reports
+}
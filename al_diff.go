@@ -0,0 +1,308 @@
+package render
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// IncrementalRenderer is a VendorRenderer which renders the changes to the
+// running config of a device instead of replacing its acls.
+type IncrementalRenderer interface {
+	VendorRenderer
+	// Incremental returns the config changing the acls and bindings of the
+	// running config to the acls, the rollback config changing them back to
+	// the running config, and the unified diff of the change.
+	Incremental(tmpl *template.Template, acls []*acl, running string) (config, rollback, diff string, err error)
+}
+
+// aclKey identifies an acl of an ip version.
+type aclKey struct {
+	IPVersion int32
+	Name      string
+}
+
+// parsedACL is the definition of an acl in config text.
+type parsedACL struct {
+	Header  string
+	Entries []string
+}
+
+// binding is an acl bound to an interface. The control plane policy is
+// bound with IPVersion 0 and no Name.
+type binding struct {
+	Intf      string
+	Direction string
+	IPVersion int32
+	Name      string
+}
+
+// parsedConfig holds the acls and bindings of Cisco style config text.
+type parsedConfig struct {
+	acls     map[aclKey]*parsedACL
+	bindings map[binding]bool
+}
+
+// policyDirections maps service-policy directions to binding directions.
+var policyDirections = map[string]string{"input": "in", "output": "out"}
+
+// parseCiscoConfig parses the acl definitions and the interface and control
+// plane bindings of Cisco style config text, e.g. a running config.
+func parseCiscoConfig(text string) *parsedConfig {
+	c := &parsedConfig{make(map[aclKey]*parsedACL), make(map[binding]bool)}
+	var cur *parsedACL
+	var intf string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, "\r")
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] == "!" || fields[0] == "exit" {
+			continue
+		}
+		if line[0] != ' ' && line[0] != '\t' {
+			cur, intf = nil, ""
+			switch {
+			case len(fields) >= 3 && (fields[0] == "ip" || fields[0] == "ipv6") && fields[1] == "access-list":
+				ipVer := int32(4)
+				if fields[0] == "ipv6" {
+					ipVer = 6
+				}
+				cur = &parsedACL{Header: strings.Join(fields, " ")}
+				c.acls[aclKey{ipVer, fields[len(fields)-1]}] = cur
+			case fields[0] == "interface" && len(fields) == 2:
+				intf = fields[1]
+			case line == "control-plane" || line == "system control-plane":
+				intf = "controlplane"
+			}
+			continue
+		}
+		switch {
+		case cur != nil:
+			cur.Entries = append(cur.Entries, strings.Join(fields, " "))
+		case intf != "" && len(fields) == 3 && fields[0] == "service-policy":
+			c.bindings[binding{intf, policyDirections[fields[1]], 0, ""}] = true
+		case intf != "" && len(fields) == 4 && fields[0] == "ip" && fields[1] == "access-group":
+			c.bindings[binding{intf, fields[3], 4, fields[2]}] = true
+		case intf != "" && len(fields) == 4 && fields[0] == "ipv6" &&
+			(fields[1] == "traffic-filter" || fields[1] == "access-group"):
+			c.bindings[binding{intf, fields[3], 6, fields[2]}] = true
+		}
+	}
+	return c
+}
+
+// lines returns the acl as canonical config lines.
+func (a *parsedACL) lines() []string {
+	if a == nil {
+		return nil
+	}
+	lines := []string{a.Header}
+	for _, e := range a.Entries {
+		lines = append(lines, " "+e)
+	}
+	return lines
+}
+
+// line formats the binding as a config line, with the ipv6Filter keyword of
+// the vendor for IPv6 acls.
+func (b binding) line(ipv6Filter string) string {
+	if b.Name == "" {
+		return fmt.Sprintf("%s service-policy %s", b.Intf, b.Direction)
+	}
+	if b.IPVersion == 6 {
+		return fmt.Sprintf("%s ipv6 %s %s %s", b.Intf, ipv6Filter, b.Name, b.Direction)
+	}
+	return fmt.Sprintf("%s ip access-group %s %s", b.Intf, b.Name, b.Direction)
+}
+
+// isPrefix reports whether a is a prefix of b.
+func isPrefix(a, b []string) bool {
+	if len(a) > len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// removed returns the entries of a missing from b if b is a subsequence of
+// a, and false otherwise.
+func removed(a, b []string) ([]string, bool) {
+	var gone []string
+	j := 0
+	for _, e := range a {
+		if j < len(b) && e == b[j] {
+			j++
+			continue
+		}
+		gone = append(gone, e)
+	}
+	return gone, j == len(b)
+}
+
+// hasSeq reports whether the acl entry starts with a sequence number.
+func hasSeq(entry string) bool {
+	fields := strings.Fields(entry)
+	return len(fields) > 1 && strings.Trim(fields[0], "0123456789") == ""
+}
+
+// noEntry returns the command deleting the acl entry, by sequence number if
+// the entry has one.
+func noEntry(entry string) string {
+	if hasSeq(entry) {
+		return "no " + strings.Fields(entry)[0]
+	}
+	return "no " + entry
+}
+
+// Incremental appends or deletes the changed entries of acls whose running
+// entries are a prefix or a superset of the wanted ones, and replaces the
+// other acls as Render does. Bindings are added and removed one by one. The
+// control plane policy binds every acl of the control plane.
+//
+// The rollback deletes the appended entries, adds the deleted ones back and
+// restores the running definition of replaced acls. Deleted entries without
+// sequence numbers cannot be added back in place, so their acl is restored
+// whole. Bindings the push removed are added back, only those it added are
+// removed.
+func (r ciscoRenderer) Incremental(tmpl *template.Template, acls []*acl, running string) (string, string, string, error) {
+	run := parseCiscoConfig(running)
+	var config, rollback []string
+	var before, after []string
+	for _, a := range acls {
+		key := aclKey{a.IPVersion, a.Name}
+		want := parseCiscoConfig(a.Data).acls[key]
+		if want == nil {
+			return "", "", "", fmt.Errorf("acl %s: no ip version %d definition in data", a.Name, a.IPVersion)
+		}
+		have := run.acls[key]
+		before = append(before, have.lines()...)
+		after = append(after, want.lines()...)
+
+		// undo holds the rollback of the definition, restore is set if the
+		// running definition is to be restored whole
+		var undo []string
+		replaced, restore := false, false
+		switch gone, ok := removed(entriesOf(have), want.Entries); {
+		case have != nil && len(have.Entries) == len(want.Entries) && isPrefix(have.Entries, want.Entries):
+		case have != nil && isPrefix(have.Entries, want.Entries):
+			config = append(config, want.Header)
+			undo = append(undo, have.Header)
+			for _, e := range want.Entries[len(have.Entries):] {
+				config = append(config, " "+e)
+				undo = append(undo, " "+noEntry(e))
+			}
+		case have != nil && ok:
+			config = append(config, want.Header)
+			undo = append(undo, have.Header)
+			for _, e := range gone {
+				config = append(config, " "+noEntry(e))
+				undo = append(undo, " "+e)
+				restore = restore || !hasSeq(e)
+			}
+		default:
+			full, err := r.Render(tmpl, []*acl{a})
+			if err != nil {
+				return "", "", "", err
+			}
+			config = append(config, strings.TrimSuffix(full, "\n"))
+			replaced = true
+			restore = have != nil
+		}
+
+		// bindings
+		var missing, bound []*intfDir
+		var wantLines, haveLines []string
+		wanted := make(map[binding]bool)
+		for _, id := range a.IntfDirection {
+			b := binding{id.Intf, id.Direction, a.IPVersion, a.Name}
+			wanted[b] = true
+			wantLines = append(wantLines, b.line(r.ipv6Filter))
+			policy := binding{Intf: id.Intf, Direction: id.Direction}
+			switch {
+			case run.bindings[b]:
+				haveLines = append(haveLines, b.line(r.ipv6Filter))
+				bound = append(bound, id)
+			case id.Intf == "controlplane" && run.bindings[policy]:
+				haveLines = append(haveLines, b.line(r.ipv6Filter))
+				bound = append(bound, id)
+			default:
+				missing = append(missing, id)
+			}
+		}
+		var extra []*intfDir
+		for b := range run.bindings {
+			if b.Name == a.Name && b.IPVersion == a.IPVersion && !wanted[b] {
+				extra = append(extra, &intfDir{b.Intf, b.Direction})
+				haveLines = append(haveLines, b.line(r.ipv6Filter))
+			}
+		}
+		sort.Slice(extra, func(i, j int) bool {
+			return extra[i].Intf+" "+extra[i].Direction < extra[j].Intf+" "+extra[j].Direction
+		})
+		sort.Strings(haveLines)
+		sort.Strings(wantLines)
+		before = append(before, haveLines...)
+		after = append(after, wantLines...)
+		if len(missing) > 0 && !replaced {
+			bind, err := executeACLs(tmpl, "bind", []*acl{{a.Name, a.IPVersion, "", missing}})
+			if err != nil {
+				return "", "", "", err
+			}
+			config = append(config, strings.TrimSuffix(bind, "\n"))
+		}
+		if len(extra) > 0 {
+			unbind, err := r.Rollback(tmpl, []*acl{{a.Name, a.IPVersion, "", extra}})
+			if err != nil {
+				return "", "", "", err
+			}
+			config = append(config, strings.TrimSuffix(unbind, "\n"))
+		}
+
+		// rollback, the bindings first so that a created acl is unbound
+		// before it is deleted
+		if len(extra) > 0 {
+			bind, err := executeACLs(tmpl, "bind", []*acl{{a.Name, a.IPVersion, "", extra}})
+			if err != nil {
+				return "", "", "", err
+			}
+			rollback = append(rollback, strings.TrimSuffix(bind, "\n"))
+		}
+		if len(missing) > 0 {
+			unbind, err := r.Rollback(tmpl, []*acl{{a.Name, a.IPVersion, "", missing}})
+			if err != nil {
+				return "", "", "", err
+			}
+			rollback = append(rollback, strings.TrimSuffix(unbind, "\n"))
+		}
+		switch {
+		case restore:
+			data := "no " + have.Header + "\n" + strings.Join(have.lines(), "\n") + "\n"
+			full, err := r.Render(tmpl, []*acl{{a.Name, a.IPVersion, data, bound}})
+			if err != nil {
+				return "", "", "", err
+			}
+			rollback = append(rollback, strings.TrimSuffix(full, "\n"))
+		case replaced:
+			rollback = append(rollback, "no "+want.Header)
+		default:
+			rollback = append(rollback, undo...)
+		}
+	}
+	if len(config) == 0 {
+		return "", "", "", nil
+	}
+	return strings.Join(config, "\n") + "\n", strings.Join(rollback, "\n") + "\n",
+		unifiedDiff("running", "rendered", before, after), nil
+}
+
+// entriesOf returns the entries of the acl, or nil.
+func entriesOf(a *parsedACL) []string {
+	if a == nil {
+		return nil
+	}
+	return a.Entries
+}
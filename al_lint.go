@@ -0,0 +1,147 @@
+package render
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Severity is the severity of a lint finding.
+type Severity string
+
+const (
+	// LintError findings are rejected by devices or match other traffic
+	// than intended.
+	LintError Severity = "error"
+	// LintWarning findings are accepted by devices but likely mistakes.
+	LintWarning Severity = "warning"
+)
+
+// LintFinding is a problem of an access list or of one of its entries.
+type LintFinding struct {
+	ACL string
+	// Line is the line number in the acl data.
+	Line     int
+	Severity Severity
+	Msg      string
+}
+
+func (f *LintFinding) String() string {
+	return fmt.Sprintf("acl %s line %d: %s: %s", f.ACL, f.Line, f.Severity, f.Msg)
+}
+
+// portProtocols are the protocols of entries with port operators.
+var portProtocols = map[string]bool{"tcp": true, "udp": true, "sctp": true}
+
+// LintACLs checks the parsed access lists for entries which cannot be
+// parsed, invalid wildcard masks, unknown protocols and ports, duplicate
+// sequence numbers and for a missing explicit or remarked implicit deny.
+func LintACLs(acls []*ParsedACL) []*LintFinding {
+	var findings []*LintFinding
+	for _, a := range acls {
+		add := func(line int, sev Severity, format string, args ...interface{}) {
+			findings = append(findings, &LintFinding{a.Name, line, sev, fmt.Sprintf(format, args...)})
+		}
+		if !a.Extended {
+			continue
+		}
+		seqs := make(map[int]int)
+		denyRemark := false
+		var last *Entry
+		for _, e := range a.Entries {
+			if e.Seq > 0 {
+				if line, ok := seqs[e.Seq]; ok {
+					add(e.Line, LintError, "duplicate sequence number %d of line %d", e.Seq, line)
+				}
+				seqs[e.Seq] = e.Line
+			}
+			if e.Err != nil {
+				add(e.Line, LintError, "%v", e.Err)
+				continue
+			}
+			if e.Action == "" {
+				if strings.Contains(strings.ToLower(e.Remark), "implicit deny") {
+					denyRemark = true
+				}
+				continue
+			}
+			last = e
+			if !protocols[e.Protocol] {
+				if n, err := strconv.Atoi(e.Protocol); err != nil || n < 0 || n > 255 {
+					add(e.Line, LintError, "unknown protocol %q", e.Protocol)
+				}
+			}
+			for _, p := range []*PortMatch{e.SrcPort, e.DstPort} {
+				if p == nil {
+					continue
+				}
+				if !portProtocols[e.Protocol] {
+					add(e.Line, LintError, "port operator %s with protocol %s", p.Op, e.Protocol)
+				}
+				for i, port := range p.Ports {
+					if port < 0 {
+						add(e.Line, LintError, "unknown port %q", p.Names[i])
+					} else if port > 65535 {
+						add(e.Line, LintError, "port %d out of range", port)
+					}
+				}
+				if p.Op == "range" && p.Ports[0] > p.Ports[1] {
+					add(e.Line, LintError, "empty port range %d-%d", p.Ports[0], p.Ports[1])
+				}
+			}
+			if a.IPVersion == 4 {
+				for _, addr := range []Address{e.Src, e.Dst} {
+					lintWildcard(addr, func(sev Severity, msg string) { add(e.Line, sev, "%s", msg) })
+				}
+			}
+		}
+		explicitDeny := last != nil && last.Action == "deny" && (last.Protocol == "ip" || last.Protocol == "ipv6") &&
+			last.Src.Any() && last.Dst.Any()
+		if !explicitDeny && !denyRemark {
+			add(a.Line, LintWarning, "no explicit deny any any at the end and no implicit deny remark")
+		}
+	}
+	return findings
+}
+
+// lintWildcard reports IPv4 wildcard masks which look like subnet masks, are
+// discontiguous or leave address bits under the wildcard.
+func lintWildcard(a Address, report func(Severity, string)) {
+	w := uint32(a.Wildcard[0])<<24 | uint32(a.Wildcard[1])<<16 | uint32(a.Wildcard[2])<<8 | uint32(a.Wildcard[3])
+	if w == 0 || w == 0xffffffff {
+		return
+	}
+	if w&(w+1) != 0 {
+		if m := ^w; m&(m+1) == 0 {
+			report(LintError, fmt.Sprintf("wildcard mask %s looks like a subnet mask", a.Wildcard))
+			return
+		}
+		report(LintWarning, fmt.Sprintf("discontiguous wildcard mask %s", a.Wildcard))
+	}
+	for i := range a.IP {
+		if a.IP[i]&a.Wildcard[i] != 0 {
+			report(LintWarning, fmt.Sprintf("address %s has bits set under wildcard mask %s", a.IP, a.Wildcard))
+			return
+		}
+	}
+}
+
+// LintData parses the Cisco IOS/XE access lists of acl data and lints them.
+func LintData(data string) []*LintFinding {
+	return LintACLs(ParseACLs(data))
+}
+
+// lintVendors are the vendors of push items whose acl data is IOS/XE
+// syntax.
+var lintVendors = map[string]bool{"cisco": true}
+
+// lintErrors returns the number of error findings.
+func lintErrors(findings []*LintFinding) int {
+	n := 0
+	for _, f := range findings {
+		if f.Severity == LintError {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,326 @@
+package render
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// ParsedACL is a Cisco IOS/XE access list parsed from acl data.
+type ParsedACL struct {
+	Name      string
+	IPVersion int32
+	// Extended is false for standard IPv4 access lists, whose entries are
+	// not parsed.
+	Extended bool
+	// Line is the line number of the header in the data.
+	Line    int
+	Entries []*Entry
+}
+
+// Entry is an entry of an access list, a remark or a permit or deny rule.
+type Entry struct {
+	// Line is the line number of the entry in the data.
+	Line int
+	Text string
+	// Seq is the sequence number of the entry, or 0.
+	Seq    int
+	Remark string
+	// Action is "permit" or "deny", or "" for remarks.
+	Action   string
+	Protocol string
+	Src      Address
+	SrcPort  *PortMatch
+	Dst      Address
+	DstPort  *PortMatch
+	// Options are the tokens after the destination, e.g. established or log.
+	Options []string
+	// Err is set if the entry cannot be parsed.
+	Err error
+}
+
+// Address is the addresses matched by an entry, those equal to IP in the
+// bits not set in Wildcard. IPv6 prefixes have the wildcard of their length.
+type Address struct {
+	IP       net.IP
+	Wildcard net.IP
+}
+
+// PortMatch is the port operator of an entry and its ports, or -1 for port
+// names which are not known.
+type PortMatch struct {
+	Op    string
+	Names []string
+	Ports []int
+}
+
+// Range returns the lowest and highest port matched by eq, lt, gt and range
+// of known ports. ok is false for neq, several ports and unknown ports.
+func (p *PortMatch) Range() (lo, hi int, ok bool) {
+	if p == nil {
+		return 0, 65535, true
+	}
+	for _, port := range p.Ports {
+		if port < 0 {
+			return 0, 0, false
+		}
+	}
+	switch {
+	case p.Op == "eq" && len(p.Ports) == 1:
+		return p.Ports[0], p.Ports[0], true
+	case p.Op == "lt":
+		return 0, p.Ports[0] - 1, true
+	case p.Op == "gt":
+		return p.Ports[0] + 1, 65535, true
+	case p.Op == "range":
+		return p.Ports[0], p.Ports[1], true
+	}
+	return 0, 0, false
+}
+
+// Any reports whether the address matches every address.
+func (a Address) Any() bool {
+	for _, b := range a.Wildcard {
+		if b != 0xff {
+			return false
+		}
+	}
+	return true
+}
+
+// Contains reports whether every address of b is an address of a.
+func (a Address) Contains(b Address) bool {
+	if len(a.IP) != len(b.IP) {
+		return false
+	}
+	for i := range a.IP {
+		// the bits fixed by a must be fixed by b to the same value
+		fixed := ^a.Wildcard[i]
+		if b.Wildcard[i]&fixed != 0 || a.IP[i]&fixed != b.IP[i]&fixed {
+			return false
+		}
+	}
+	return true
+}
+
+// Overlaps reports whether a and b have an address in common.
+func (a Address) Overlaps(b Address) bool {
+	if len(a.IP) != len(b.IP) {
+		return false
+	}
+	for i := range a.IP {
+		fixed := ^a.Wildcard[i] & ^b.Wildcard[i]
+		if a.IP[i]&fixed != b.IP[i]&fixed {
+			return false
+		}
+	}
+	return true
+}
+
+// protocols are the protocol keywords of IOS access lists.
+var protocols = map[string]bool{
+	"ahp": true, "eigrp": true, "esp": true, "gre": true, "icmp": true, "igmp": true, "ip": true,
+	"ipinip": true, "ipv6": true, "nos": true, "ospf": true, "pcp": true, "pim": true, "sctp": true,
+	"tcp": true, "udp": true,
+}
+
+// portNames are the port names of IOS access lists.
+var portNames = map[string]int{
+	"bgp": 179, "biff": 512, "bootpc": 68, "bootps": 67, "chargen": 19, "cmd": 514, "daytime": 13,
+	"discard": 9, "domain": 53, "echo": 7, "exec": 512, "finger": 79, "ftp": 21, "ftp-data": 20,
+	"gopher": 70, "hostname": 101, "ident": 113, "irc": 194, "isakmp": 500, "klogin": 543,
+	"kshell": 544, "ldp": 646, "login": 513, "lpd": 515, "mail": 25, "netbios-dgm": 138,
+	"netbios-ns": 137, "netbios-ss": 139, "nntp": 119, "ntp": 123, "pim-auto-rp": 496, "pop2": 109,
+	"pop3": 110, "rip": 520, "smtp": 25, "snmp": 161, "snmptrap": 162, "sunrpc": 111, "syslog": 514,
+	"tacacs": 49, "talk": 517, "telnet": 23, "tftp": 69, "time": 37, "uucp": 540, "who": 513,
+	"whois": 43, "www": 80, "xdmcp": 177,
+}
+
+// portOps are the port operators and their number of ports, 0 for one or more.
+var portOps = map[string]int{"eq": 0, "neq": 0, "lt": 1, "gt": 1, "range": 2}
+
+// ParseACLs parses the IPv4 and IPv6 access lists of Cisco IOS/XE acl
+// data. Entries which cannot be parsed have Err set.
+func ParseACLs(data string) []*ParsedACL {
+	var acls []*ParsedACL
+	var cur *ParsedACL
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(line, "\r")
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] == "!" || fields[0] == "exit" {
+			continue
+		}
+		if line[0] != ' ' && line[0] != '\t' {
+			cur = nil
+			switch {
+			case len(fields) == 4 && fields[0] == "ip" && fields[1] == "access-list":
+				cur = &ParsedACL{Name: fields[3], IPVersion: 4, Extended: fields[2] == "extended", Line: i + 1}
+			case len(fields) == 3 && fields[0] == "ipv6" && fields[1] == "access-list":
+				cur = &ParsedACL{Name: fields[2], IPVersion: 6, Extended: true, Line: i + 1}
+			}
+			if cur != nil {
+				acls = append(acls, cur)
+			}
+			continue
+		}
+		if cur == nil || !cur.Extended {
+			continue
+		}
+		e := &Entry{Line: i + 1, Text: strings.Join(fields, " ")}
+		e.Err = e.parse(fields, cur.IPVersion)
+		cur.Entries = append(cur.Entries, e)
+	}
+	return acls
+}
+
+// parse sets the fields of the entry from its tokens.
+func (e *Entry) parse(fields []string, ipVer int32) error {
+	if len(fields) > 1 && fields[0] == "sequence" {
+		fields = fields[1:]
+	}
+	if seq, err := strconv.Atoi(fields[0]); err == nil {
+		if seq <= 0 {
+			return fmt.Errorf("invalid sequence number %s", fields[0])
+		}
+		e.Seq = seq
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return fmt.Errorf("missing action")
+	}
+	switch fields[0] {
+	case "remark":
+		e.Remark = strings.Join(fields[1:], " ")
+		return nil
+	case "permit", "deny":
+		e.Action = fields[0]
+	default:
+		return fmt.Errorf("unknown action %q", fields[0])
+	}
+	if len(fields) < 2 {
+		return fmt.Errorf("missing protocol")
+	}
+	e.Protocol = fields[1]
+	rest := fields[2:]
+	var err error
+	if e.Src, rest, err = parseAddress(rest, ipVer); err != nil {
+		return fmt.Errorf("source: %v", err)
+	}
+	if e.SrcPort, rest, err = parsePorts(rest); err != nil {
+		return fmt.Errorf("source port: %v", err)
+	}
+	if e.Dst, rest, err = parseAddress(rest, ipVer); err != nil {
+		return fmt.Errorf("destination: %v", err)
+	}
+	if e.DstPort, rest, err = parsePorts(rest); err != nil {
+		return fmt.Errorf("destination port: %v", err)
+	}
+	e.Options = rest
+	return nil
+}
+
+// parseAddress parses any, host A, A W for IPv4 or A/len for IPv6 from the
+// tokens, and returns the tokens after it.
+func parseAddress(tokens []string, ipVer int32) (Address, []string, error) {
+	size := net.IPv4len
+	if ipVer == 6 {
+		size = net.IPv6len
+	}
+	if len(tokens) == 0 {
+		return Address{}, nil, fmt.Errorf("missing address")
+	}
+	switch tokens[0] {
+	case "any":
+		return Address{make(net.IP, size), wildcard(0, size)}, tokens[1:], nil
+	case "host":
+		if len(tokens) < 2 {
+			return Address{}, nil, fmt.Errorf("missing host address")
+		}
+		ip := parseIP(tokens[1], size)
+		if ip == nil {
+			return Address{}, nil, fmt.Errorf("invalid host address %q", tokens[1])
+		}
+		return Address{ip, wildcard(size*8, size)}, tokens[2:], nil
+	}
+	if ipVer == 6 {
+		_, n, err := net.ParseCIDR(tokens[0])
+		if err != nil || n.IP.To4() != nil {
+			return Address{}, nil, fmt.Errorf("invalid prefix %q", tokens[0])
+		}
+		ones, _ := n.Mask.Size()
+		return Address{n.IP, wildcard(ones, size)}, tokens[1:], nil
+	}
+	if len(tokens) < 2 {
+		return Address{}, nil, fmt.Errorf("missing wildcard mask of %s", tokens[0])
+	}
+	ip, w := parseIP(tokens[0], size), parseIP(tokens[1], size)
+	if ip == nil || w == nil {
+		return Address{}, nil, fmt.Errorf("invalid address %s %s", tokens[0], tokens[1])
+	}
+	return Address{ip, w}, tokens[2:], nil
+}
+
+// parseIP parses an IPv4 or IPv6 address of size bytes.
+func parseIP(s string, size int) net.IP {
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil
+	}
+	if size == net.IPv4len {
+		return ip.To4()
+	}
+	if ip.To4() != nil {
+		return nil
+	}
+	return ip
+}
+
+// wildcard returns the wildcard mask of a prefix of length ones.
+func wildcard(ones, size int) net.IP {
+	mask := net.CIDRMask(ones, size*8)
+	w := make(net.IP, size)
+	for i := range mask {
+		w[i] = ^mask[i]
+	}
+	return w
+}
+
+// parsePorts parses a port operator and its ports from the tokens if they
+// start with one, and returns the tokens after it.
+func parsePorts(tokens []string) (*PortMatch, []string, error) {
+	if len(tokens) == 0 {
+		return nil, tokens, nil
+	}
+	n, ok := portOps[tokens[0]]
+	if !ok {
+		return nil, tokens, nil
+	}
+	p := &PortMatch{Op: tokens[0]}
+	rest := tokens[1:]
+	for len(rest) > 0 && (n == 0 || len(p.Ports) < n) {
+		port, known := portNumber(rest[0])
+		if !known && (n == 0 && len(p.Ports) > 0) {
+			break
+		}
+		p.Names = append(p.Names, rest[0])
+		p.Ports = append(p.Ports, port)
+		rest = rest[1:]
+	}
+	if len(p.Ports) == 0 || (n > 0 && len(p.Ports) != n) {
+		return nil, nil, fmt.Errorf("%s needs %s", p.Op, map[int]string{0: "ports", 1: "a port", 2: "two ports"}[n])
+	}
+	return p, rest, nil
+}
+
+// portNumber returns the number of a port number or name, and false for
+// names which are not known.
+func portNumber(s string) (int, bool) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n, true
+	}
+	if n, ok := portNames[s]; ok {
+		return n, true
+	}
+	return -1, false
+}
@@ -2,9 +2,8 @@
 package render
 
 import (
-	"bytes"
+	"fmt"
 	"path"
-	"strings"
 	"text/template"
 
 	".../base/go/runfiles"
@@ -38,13 +37,11 @@ func loadTemplate(templateName string) *template.Template {
 }
 
 // loadACL parses ACLPushItem pb to map.
-func loadACL(pushPb []*spb.ACLPushItem) (canaryACL map[string][]*acl, globalACL map[string][]*acl, deviceVendor map[string]string) {
+func loadACL(pushPb []*spb.ACLPushItem) (deviceACL map[string][]*acl, deviceVendor map[string]string) {
 	var aclData, aclName, deviceName, vendor string
 	var ipVer int32
-	var canary bool
 	// map device name to a list of Acl
-	canaryMap := make(map[string][]*acl)
-	globalMap := make(map[string][]*acl)
+	aclMap := make(map[string][]*acl)
 
 	// map device name to vendor
 	vendorMap := make(map[string]string)
@@ -54,75 +51,114 @@ func loadACL(pushPb []*spb.ACLPushItem) (canaryACL map[string][]*acl, globalACL
 		aclData = *item.Data
 		aclName = *item.AclName
 		vendor = *item.Vendor
-		if vendor == "cisco" {
-			aclName = strings.Split(aclName, ".")[2]
-		}
+		aclName = normalizeName(vendor, aclName)
+		ipVer = 0
 		if item.IpVersion != nil {
 			ipVer = *item.IpVersion
 		}
 		for _, enforcePoint := range item.EnforcePoint {
 			deviceName = *enforcePoint.DeviceName
 			vendorMap[deviceName] = vendor
-			canary = false
-			for _, tag := range enforcePoint.Tags {
-				if (*tag.Type == "Canary") && (*tag.Value == "True") {
-					canary = true
-					break
-				}
-			}
 			intfs := make([]*intfDir, 0, len(enforcePoint.Units))
 			for _, unit := range enforcePoint.Units {
 				//add intf, direction
 				intfs = append(intfs, &intfDir{*unit.Name, *unit.Direction})
 			}
-			if canary {
-				canaryMap[deviceName] = append(canaryMap[deviceName], &acl{aclName, ipVer, aclData, intfs})
-				continue
-			}
-			globalMap[deviceName] = append(globalMap[deviceName], &acl{aclName, ipVer, aclData, intfs})
+			aclMap[deviceName] = append(aclMap[deviceName], &acl{aclName, ipVer, aclData, intfs})
 		}
 	}
-	return canaryMap, globalMap, vendorMap
+	return aclMap, vendorMap
 }
 
-// PreparePush returns canary and global acl map keyed on device name.
-func PreparePush(pushPb []*spb.ACLPushItem) ([]map[string]string, []error) {
-	var err error
-	var errs []error
-	canaryMap, globalMap, vendorMap := loadACL(pushPb)
-	ciscoACLTmpl := loadTemplate("cisco_acl")
-	aclMaps := []map[string][]*acl{canaryMap, globalMap}
-	results := make([]map[string]string, 2, 2)
-	for idx := range aclMaps {
-		deviceACL := make(map[string]string)
-		for dev := range aclMaps[idx] {
-			deviceACL[dev], err = configHandler(vendorMap[dev], aclMaps[idx][dev], ciscoACLTmpl)
-			if err != nil {
-				errs = append(errs, err)
-			}
-		}
-		results[idx] = deviceACL
-	}
-	return results, errs
+// PushOptions configures PreparePushWithOptions.
+type PushOptions struct {
+	// Policy decides what happens to push items which fail ValidatePush.
+	Policy ValidationPolicy
+	// Rollout decides the waves of the devices.
+	Rollout RolloutRules
+	// Lint runs LintData on the acl data of the vendors with IOS/XE syntax.
+	// Devices with lint errors are not rendered.
+	Lint bool
+	// Running holds the running config of devices keyed on device name.
+	// The config of these devices only changes their running config, if
+	// their vendor renderer is an IncrementalRenderer.
+	Running map[string]string
+}
+
+// PreparePush renders the config of every device of the push items, in a
+// canary and a global wave. It rejects the push if any push item is invalid.
+func PreparePush(pushPb []*spb.ACLPushItem) (*PushResult, error) {
+	return PreparePushWithOptions(pushPb, PushOptions{})
 }
 
-// configHandler renders template, returns acl config as string.
-func configHandler(vendor string, aclData []*acl, aclTmpl *template.Template) (string, error) {
-	var config string
-	b := bytes.NewBuffer([]byte{})
-	if vendor == "cisco" {
-		for _, data := range aclData {
-			if err := aclTmpl.ExecuteTemplate(b, "layout", data); err != nil {
-				return "", err
+// PreparePushWithOptions renders the config of every device of the push
+// items in the waves of the rollout rules. It returns the *ValidationReport
+// as error if push items are invalid and the policy rejects them, and the
+// problems of skipped push items in the Errors of the result otherwise.
+func PreparePushWithOptions(pushPb []*spb.ACLPushItem, opts PushOptions) (*PushResult, error) {
+	report := ValidatePush(pushPb)
+	if !report.Valid() && opts.Policy == RejectInvalid {
+		return nil, report
+	}
+	items := validItems(pushPb, report)
+	plan, err := PlanRollout(items, &opts.Rollout)
+	if err != nil {
+		return nil, err
+	}
+	res := &PushResult{Plan: plan}
+	for _, e := range report.Errors {
+		res.Errors = append(res.Errors, e)
+	}
+
+	aclMap, vendorMap := loadACL(items)
+	sources := sourceACLNames(items)
+	templates := make(map[string]*template.Template)
+	lints := make(map[string][]*LintFinding)
+	for idx, wave := range plan.Waves {
+		for _, dev := range wave.Devices {
+			d := newDeviceResult(dev, vendorMap[dev], idx, aclMap[dev])
+			d.SourceACLNames = sources[dev]
+			res.Devices = append(res.Devices, d)
+			if opts.Lint && lintVendors[d.Vendor] {
+				for _, a := range aclMap[dev] {
+					findings, ok := lints[a.Data]
+					if !ok {
+						findings = LintData(a.Data)
+						lints[a.Data] = findings
+					}
+					d.Lint = append(d.Lint, findings...)
+				}
+				if n := lintErrors(d.Lint); n > 0 {
+					d.Errors = append(d.Errors, fmt.Errorf("%d lint errors", n))
+					continue
+				}
 			}
+			r, err := vendorRenderer(d.Vendor)
+			if err != nil {
+				d.Errors = append(d.Errors, err)
+				continue
+			}
+			name := r.TemplateName()
+			if name != "" && templates[name] == nil {
+				templates[name] = loadTemplate(name)
+			}
+			var config, rollback string
+			if ir, ok := r.(IncrementalRenderer); ok && opts.Running[dev] != "" {
+				config, rollback, d.Diff, err = ir.Incremental(templates[name], aclMap[dev], opts.Running[dev])
+				d.Incremental = err == nil
+			} else {
+				config, err = r.Render(templates[name], aclMap[dev])
+				if err == nil {
+					rollback, err = r.Rollback(templates[name], aclMap[dev])
+				}
+			}
+			if err != nil {
+				d.Errors = append(d.Errors, err)
+				continue
+			}
+			d.setConfig(config)
+			d.Rollback = rollback
 		}
-		config = b.String()
-		config = strings.Replace(config, "\nexit", "", -1)
-	} else if vendor == "juniper" {
-		for _, data := range aclData {
-			b.WriteString(data.Data)
-		}
-		config = b.String()
 	}
-	return config, nil
+	return res, nil
 }
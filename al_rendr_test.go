@@ -1,8 +1,12 @@
 package render
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"path"
 	"reflect"
+	"strings"
 	"testing"
 
 	".../file/base/go/file"
@@ -188,25 +192,654 @@ func TestPreparePush(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		wantCanary := make(map[string]string)
-		wantGlobal := make(map[string]string)
-		acls, err := PreparePush(test.input)
+		checkPush(t, test.name, test.input)
+	}
+	res, err := PreparePush(aclPushPb)
+	if err != nil {
+		t.Fatalf("PreparePush error: %v", err)
+	}
+	for _, dev := range []string{"jp-t1", "jp1"} {
+		if d := res.Device(dev); d == nil || res.Plan.Waves[d.Wave].Name != "canary" {
+			t.Errorf("PreparePush did not put canary %s in the canary wave: %+v", dev, d)
+		}
+	}
+}
+
+func TestPreparePushUnknownVendor(t *testing.T) {
+	input := []*spb.ACLPushItem{
+		{
+			EnforcePoint: []*spb.ACLEnforcementPoint{
+				{DeviceName: proto.String("us-mtv-fw1")},
+			},
+			AclName: proto.String("edge_in"),
+			Vendor:  proto.String("acme"),
+			Data:    proto.String("deny any\n"),
+		},
+	}
+	res, err := PreparePush(input)
+	if err != nil {
+		t.Fatalf("PreparePush error: %v", err)
+	}
+	if d := res.Device("us-mtv-fw1"); d == nil || len(d.Errors) != 1 || d.Config != "" {
+		t.Errorf("PreparePush result for unknown vendor: got %+v, want one error and no config", d)
+	}
+}
+
+var juniperPushPb = []*spb.ACLPushItem{
+	{
+		EnforcePoint: []*spb.ACLEnforcementPoint{
+			{
+				DeviceName: proto.String("us-mtv-mx1"),
+				Units: []*spb.ACLEnforcementPoint_ACLEnforcementUnit{
+					{
+						Name:      proto.String("xe-0/0/1.100"),
+						Direction: proto.String("in"),
+					},
+					{
+						Name:      proto.String("xe-0/0/1.100"),
+						Direction: proto.String("out"),
+					},
+					{
+						Name:      proto.String("ae0"),
+						Direction: proto.String("in"),
+					},
+				},
+			},
+		},
+		AclName:   proto.String("edge_v4"),
+		IpVersion: proto.Int32(4),
+		Vendor:    proto.String("juniper"),
+		Data:      proto.String("firewall {\n    family inet {\n        replace: filter edge_v4 {\n            term deny-all {\n                then discard;\n            }\n        }\n    }\n}\n"),
+	},
+	{
+		EnforcePoint: []*spb.ACLEnforcementPoint{
+			{
+				DeviceName: proto.String("us-mtv-mx1"),
+				Units: []*spb.ACLEnforcementPoint_ACLEnforcementUnit{
+					{
+						Name:      proto.String("xe-0/0/1.100"),
+						Direction: proto.String("in"),
+					},
+				},
+			},
+		},
+		AclName:   proto.String("edge_v6"),
+		IpVersion: proto.Int32(6),
+		Vendor:    proto.String("juniper"),
+		Data:      proto.String("firewall {\n    family inet6 {\n        replace: filter edge_v6 {\n            term deny-all {\n                then discard;\n            }\n        }\n    }\n}\n"),
+	},
+	{
+		EnforcePoint: []*spb.ACLEnforcementPoint{
+			{
+				DeviceName: proto.String("us-mtv-mx2"),
+				Units: []*spb.ACLEnforcementPoint_ACLEnforcementUnit{
+					{
+						Name:      proto.String("controlplane"),
+						Direction: proto.String("in"),
+					},
+				},
+				Tags: []*spb.ACLEnforcementPoint_Tag{
+					{
+						Type:  proto.String("Canary"),
+						Value: proto.String("True"),
+					},
+				},
+			},
+		},
+		AclName:   proto.String("protect_re"),
+		IpVersion: proto.Int32(4),
+		Vendor:    proto.String("juniper"),
+		Data:      proto.String("firewall {\n    family inet {\n        replace: filter protect_re {\n            term ssh {\n                from {\n                    protocol tcp;\n                    destination-port ssh;\n                }\n                then accept;\n            }\n        }\n    }\n}\n"),
+	},
+}
+
+// checkPush compares the configs rendered from input with the golden files
+// named after the devices.
+func checkPush(t *testing.T, name string, input []*spb.ACLPushItem) {
+	res, err := PreparePush(input)
+	if err != nil {
+		t.Fatalf("PreparePush error for test %s: %v", name, err)
+	}
+	for _, d := range res.Devices {
+		if d.Errors != nil {
+			t.Errorf("PreparePush errors for test %s, device %s: %v", name, d.Device, d.Errors)
+		}
+		if want := getConfigFromFile(d.Device, t); d.Config != want {
+			t.Errorf("acl mismatch for test: %s, device: %s, got: %q, want: %q", name, d.Device, d.Config, want)
+		}
+	}
+}
+
+func TestPreparePushJuniper(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []*spb.ACLPushItem
+	}{
+		{name: "inet_inet6_units", input: juniperPushPb[0:2]},
+		{name: "lo0_control_plane", input: juniperPushPb[2:3]},
+	}
+	for _, test := range tests {
+		checkPush(t, test.name, test.input)
+	}
+}
+
+func TestPreparePushJuniperInvalidUnit(t *testing.T) {
+	input := []*spb.ACLPushItem{
+		{
+			EnforcePoint: []*spb.ACLEnforcementPoint{
+				{
+					DeviceName: proto.String("us-mtv-mx3"),
+					Units: []*spb.ACLEnforcementPoint_ACLEnforcementUnit{
+						{
+							Name:      proto.String("xe-0/0/2."),
+							Direction: proto.String("in"),
+						},
+					},
+				},
+			},
+			AclName:   proto.String("edge_v4"),
+			IpVersion: proto.Int32(4),
+			Vendor:    proto.String("juniper"),
+			Data:      proto.String("firewall {\n}\n"),
+		},
+	}
+	res, err := PreparePush(input)
+	if err != nil {
+		t.Fatalf("PreparePush error: %v", err)
+	}
+	if d := res.Device("us-mtv-mx3"); d == nil || len(d.Errors) != 1 {
+		t.Errorf("PreparePush result: got %+v, want one invalid unit error", d)
+	}
+}
+
+// switchPushItem returns a push item of the vendor binding the acl to units
+// of the device.
+func switchPushItem(vendor, device, aclName string, ipVersion int32, data string, canary bool, units ...string) *spb.ACLPushItem {
+	ep := &spb.ACLEnforcementPoint{DeviceName: proto.String(device)}
+	for i := 0; i+1 < len(units); i += 2 {
+		ep.Units = append(ep.Units, &spb.ACLEnforcementPoint_ACLEnforcementUnit{
+			Name:      proto.String(units[i]),
+			Direction: proto.String(units[i+1]),
+		})
+	}
+	if canary {
+		ep.Tags = []*spb.ACLEnforcementPoint_Tag{{Type: proto.String("Canary"), Value: proto.String("True")}}
+	}
+	return &spb.ACLPushItem{
+		EnforcePoint: []*spb.ACLEnforcementPoint{ep},
+		AclName:      proto.String(aclName),
+		IpVersion:    proto.Int32(ipVersion),
+		Vendor:       proto.String(vendor),
+		Data:         proto.String(data),
+	}
+}
+
+func TestPreparePushAristaNXOS(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []*spb.ACLPushItem
+	}{
+		{name: "eos_inet_inet6", input: []*spb.ACLPushItem{
+			switchPushItem("arista", "us-mtv-eos1", "edge_v4", 4,
+				"no ip access-list edge_v4\nip access-list edge_v4\n 10 deny ip any any\nexit\n", false,
+				"Ethernet1", "in", "Vlan100", "out"),
+			switchPushItem("arista", "us-mtv-eos1", "edge_v6", 6,
+				"no ipv6 access-list edge_v6\nipv6 access-list edge_v6\n 10 deny ipv6 any any\nexit\n", false,
+				"Ethernet1", "in"),
+		}},
+		{name: "eos_control_plane", input: []*spb.ACLPushItem{
+			switchPushItem("arista", "us-mtv-eos2", "protect_cp", 4,
+				"no ip access-list protect_cp\nip access-list protect_cp\n 10 permit tcp any any eq ssh\nexit\n", true,
+				"controlplane", "in"),
+		}},
+		{name: "nxos_inet_inet6", input: []*spb.ACLPushItem{
+			switchPushItem("nxos", "us-mtv-n9k1", "edge_v4", 4,
+				"no ip access-list edge_v4\nip access-list edge_v4\n 10 deny ip any any\nexit\n", false,
+				"Ethernet1/1", "in", "Vlan100", "out"),
+			switchPushItem("nxos", "us-mtv-n9k1", "edge_v6", 6,
+				"no ipv6 access-list edge_v6\nipv6 access-list edge_v6\n 10 deny ipv6 any any\nexit\n", false,
+				"Ethernet1/1", "in"),
+		}},
+		{name: "nxos_control_plane", input: []*spb.ACLPushItem{
+			switchPushItem("nxos", "us-mtv-n9k2", "protect_cp", 4,
+				"no ip access-list protect_cp\nip access-list protect_cp\n 10 permit tcp any any eq 22\nexit\n", true,
+				"controlplane", "in"),
+		}},
+	}
+	for _, test := range tests {
+		checkPush(t, test.name, test.input)
+	}
+}
+
+func TestValidatePush(t *testing.T) {
+	valid := switchPushItem("arista", "us-mtv-eos1", "edge_v4", 4, "ip access-list edge_v4\n", false, "Ethernet1", "in")
+	tests := []struct {
+		name  string
+		input *spb.ACLPushItem
+		// want holds the enforce point index of each error, -1 for the item.
+		want []int
+	}{
+		{name: "valid", input: valid},
+		{name: "missing_data", input: &spb.ACLPushItem{
+			EnforcePoint: valid.EnforcePoint,
+			AclName:      proto.String("edge_v4"),
+			IpVersion:    proto.Int32(4),
+			Vendor:       proto.String("arista"),
+		}, want: []int{-1}},
+		{name: "bad_ip_version", input: switchPushItem("arista", "us-mtv-eos1", "edge_v4", 5, "", false), want: []int{-1}},
+		{name: "bad_direction_and_intf", input: switchPushItem("arista", "us-mtv-eos1", "edge_v4", 4, "", false,
+			"Ethernet 1", "in", "Ethernet2", "both"), want: []int{0, 0}},
+		{name: "missing_device_and_tag", input: &spb.ACLPushItem{
+			EnforcePoint: []*spb.ACLEnforcementPoint{
+				{DeviceName: proto.String("us-mtv-eos1"), Tags: []*spb.ACLEnforcementPoint_Tag{{Type: proto.String("Canary")}}},
+				{},
+			},
+			AclName: proto.String("edge_v4"),
+			Vendor:  proto.String("arista"),
+			Data:    proto.String(""),
+		}, want: []int{0, 1}},
+	}
+	for _, test := range tests {
+		var got []int
+		for _, e := range ValidatePush([]*spb.ACLPushItem{test.input}).Errors {
+			got = append(got, e.EnforcePoint)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ValidatePush for test %s: got errors at %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestPreparePushValidationPolicy(t *testing.T) {
+	input := []*spb.ACLPushItem{
+		switchPushItem("arista", "us-mtv-eos1", "edge_v4", 4, "ip access-list edge_v4\n", false, "Ethernet1", "up"),
+		switchPushItem("juniper", "us-mtv-mx4", "edge_v4", 4, "firewall {\n}\n", false),
+	}
+	res, err := PreparePushWithOptions(input, PushOptions{Policy: RejectInvalid})
+	if report, ok := err.(*ValidationReport); res != nil || !ok || len(report.Errors) != 1 {
+		t.Errorf("RejectInvalid: got result %v, error %v, want one invalid item", res, err)
+	}
+	res, err = PreparePushWithOptions(input, PushOptions{Policy: SkipInvalid})
+	if err != nil {
+		t.Fatalf("SkipInvalid: PreparePush error: %v", err)
+	}
+	if _, ok := res.Errors[0].(*ItemError); len(res.Errors) != 1 || !ok {
+		t.Errorf("SkipInvalid: got errors %v, want one *ItemError", res.Errors)
+	}
+	if len(res.Devices) != 1 || res.Devices[0].Device != "us-mtv-mx4" || res.Devices[0].Config != "firewall {\n}\n" {
+		t.Errorf("SkipInvalid: got devices %+v, want only us-mtv-mx4", res.Devices)
+	}
+}
+
+func TestPlanRollout(t *testing.T) {
+	var input []*spb.ACLPushItem
+	for _, d := range []struct{ device, region, canary string }{
+		{"jp-t1", "APAC", "True"},
+		{"us1", "AMER", ""},
+		{"us2", "AMER", ""},
+		{"us3", "AMER", ""},
+		{"us4", "AMER", ""},
+		{"eu1", "EMEA", ""},
+		{"sg1", "APAC", ""},
+		{"lab1", "", ""},
+	} {
+		ep := &spb.ACLEnforcementPoint{DeviceName: proto.String(d.device)}
+		if d.region != "" {
+			ep.Tags = append(ep.Tags, &spb.ACLEnforcementPoint_Tag{Type: proto.String("Region"), Value: proto.String(d.region)})
+		}
+		if d.canary != "" {
+			ep.Tags = append(ep.Tags, &spb.ACLEnforcementPoint_Tag{Type: proto.String("Canary"), Value: proto.String(d.canary)})
+		}
+		input = append(input, &spb.ACLPushItem{EnforcePoint: []*spb.ACLEnforcementPoint{ep}})
+	}
+	waveNames := func(p *RolloutPlan) []string {
+		var names []string
+		for _, w := range p.Waves {
+			names = append(names, fmt.Sprintf("%s%v", w.Name, w.Devices))
+		}
+		return names
+	}
+
+	tests := []struct {
+		name  string
+		rules RolloutRules
+		want  []string
+	}{
+		{name: "canary_global", want: []string{"canary[jp-t1]", "global[eu1 lab1 sg1 us1 us2 us3 us4]"}},
+		{name: "region_order", rules: RolloutRules{RegionOrder: []string{"APAC", "AMER"}},
+			want: []string{"canary[jp-t1]", "APAC[sg1]", "AMER[us1 us2 us3 us4]", "EMEA[eu1]", "no region[lab1]"}},
+		{name: "max_devices", rules: RolloutRules{RegionOrder: []string{"AMER"}, MaxDevices: 3},
+			want: []string{"canary[jp-t1]", "AMER 1/2[us1 us2 us3]", "AMER 2/2[us4]", "APAC[sg1]", "EMEA[eu1]", "no region[lab1]"}},
+		{name: "custom_canary", rules: RolloutRules{CanaryTags: []RolloutTag{{"Region", "EMEA"}}},
+			want: []string{"canary[eu1]", "global[jp-t1 lab1 sg1 us1 us2 us3 us4]"}},
+	}
+	for _, test := range tests {
+		plan, err := PlanRollout(input, &test.rules)
+		if err != nil {
+			t.Fatalf("PlanRollout error for test %s: %v", test.name, err)
+		}
+		if got := waveNames(plan); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("PlanRollout for test %s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestPlanRolloutBatches(t *testing.T) {
+	var input []*spb.ACLPushItem
+	for i := 0; i < 20; i++ {
+		input = append(input, switchPushItem("arista", fmt.Sprintf("dev%02d", i), "edge_v4", 4, "", false))
+	}
+	rules := &RolloutRules{BatchPercents: []int{10, 50, 100}}
+	plan, err := PlanRollout(input, rules)
+	if err != nil {
+		t.Fatalf("PlanRollout error: %v", err)
+	}
+	var sizes []int
+	for _, w := range plan.Waves {
+		sizes = append(sizes, len(w.Devices))
+	}
+	if want := []int{2, 11, 7}; !reflect.DeepEqual(sizes, want) {
+		t.Errorf("PlanRollout wave sizes: got %v, want %v", sizes, want)
+	}
+	// adding a device leaves the devices of the first batch in it
+	first := plan.Waves[0].Devices
+	plan, _ = PlanRollout(append(input, switchPushItem("arista", "dev20", "edge_v4", 4, "", false)), rules)
+	if plan.WaveOf(first[0]) != 0 || plan.WaveOf(first[1]) != 0 {
+		t.Errorf("PlanRollout moved first batch %v", first)
+	}
+	if _, err := PlanRollout(input, &RolloutRules{BatchPercents: []int{50, 40}}); err == nil {
+		t.Error("PlanRollout accepted decreasing batch percentages")
+	}
+}
+
+func TestPushResult(t *testing.T) {
+	res, err := PreparePush(juniperPushPb)
+	if err != nil {
+		t.Fatalf("PreparePush error: %v", err)
+	}
+	if len(res.Plan.Waves) != 2 || len(res.Wave(0)) != 1 || len(res.Wave(1)) != 1 {
+		t.Fatalf("PreparePush waves: got %+v, want canary and global wave of one device", res.Plan.Waves)
+	}
+	d := res.Device("us-mtv-mx1")
+	want := &DeviceResult{
+		Device:     "us-mtv-mx1",
+		Vendor:     "juniper",
+		Wave:       1,
+		Config:     d.Config,
+		ACLNames:       []string{"edge_v4", "edge_v6"},
+		SourceACLNames: []string{"edge_v4", "edge_v6"},
+		Interfaces:     []string{"ae0", "xe-0/0/1.100"},
+		Rollback:       getConfigFromFile("us-mtv-mx1.rollback", t),
+	}
+	sum := sha256.Sum256([]byte(getConfigFromFile("us-mtv-mx1", t)))
+	want.Hash = hex.EncodeToString(sum[:])
+	if !reflect.DeepEqual(d, want) {
+		t.Errorf("PreparePush device result: got %+v, want %+v", d, want)
+	}
+	if failed := res.Failed(); failed != nil {
+		t.Errorf("PreparePush failed devices: %+v", failed)
+	}
+
+	// cisco acl names are normalized to their third field on the device
+	res, err = PreparePush([]*spb.ACLPushItem{
+		switchPushItem("cisco", "us-mtv-isr1", "ios.acl.edge_v4", 4,
+			"no ip access-list extended edge_v4\nip access-list extended edge_v4\n deny ip any any\nexit\n", false,
+			"GigabitEthernet0/1", "in"),
+	})
+	if err != nil {
+		t.Fatalf("PreparePush error: %v", err)
+	}
+	d = res.Device("us-mtv-isr1")
+	if !reflect.DeepEqual(d.ACLNames, []string{"edge_v4"}) || !reflect.DeepEqual(d.SourceACLNames, []string{"ios.acl.edge_v4"}) {
+		t.Errorf("PreparePush acl names: got %v from %v, want [edge_v4] from [ios.acl.edge_v4]", d.ACLNames, d.SourceACLNames)
+	}
+}
+
+func TestPreparePushRollback(t *testing.T) {
+	cisco := []*spb.ACLPushItem{
+		switchPushItem("cisco", "us-mtv-isr1", "ios.acl.edge_v4", 4,
+			"no ip access-list extended edge_v4\nip access-list extended edge_v4\n deny ip any any\nexit\n", false,
+			"GigabitEthernet0/1", "in", "controlplane", "in"),
+		switchPushItem("cisco", "us-mtv-isr1", "ios.acl.edge_v6", 6,
+			"no ipv6 access-list edge_v6\nipv6 access-list edge_v6\n deny ipv6 any any\nexit\n", false,
+			"GigabitEthernet0/1", "out"),
+	}
+	tests := []struct {
+		name  string
+		input []*spb.ACLPushItem
+	}{
+		{name: "cisco", input: cisco},
+		{name: "juniper", input: juniperPushPb},
+		{name: "arista", input: []*spb.ACLPushItem{
+			switchPushItem("arista", "us-mtv-eos1", "edge_v4", 4, "", false, "Ethernet1", "in", "controlplane", "in"),
+			switchPushItem("arista", "us-mtv-eos1", "edge_v6", 6, "", false, "Ethernet1", "in"),
+		}},
+		{name: "nxos", input: []*spb.ACLPushItem{
+			switchPushItem("nxos", "us-mtv-n9k1", "edge_v4", 4, "", false, "Ethernet1/1", "in", "controlplane", "in"),
+			switchPushItem("nxos", "us-mtv-n9k1", "edge_v6", 6, "", false, "Ethernet1/1", "out"),
+		}},
+	}
+	for _, test := range tests {
+		res, err := PreparePush(test.input)
 		if err != nil {
-			t.Errorf("PreparePush error: %v", err)
+			t.Fatalf("PreparePush error for test %s: %v", test.name, err)
+		}
+		for _, d := range res.Devices {
+			if d.Errors != nil {
+				t.Errorf("PreparePush errors for test %s, device %s: %v", test.name, d.Device, d.Errors)
+			}
+			if want := getConfigFromFile(d.Device+".rollback", t); d.Rollback != want {
+				t.Errorf("rollback mismatch for test: %s, device: %s, got: %q, want: %q", test.name, d.Device, d.Rollback, want)
+			}
+		}
+	}
+}
+
+func TestPreparePushIncremental(t *testing.T) {
+	running := map[string]string{
+		"us-mtv-isr1": "hostname us-mtv-isr1\n!\nip access-list extended edge_v4\n permit tcp any any eq 22\n deny ip any any\n!\n" +
+			"interface GigabitEthernet0/1\n ip address 10.0.0.1 255.255.255.0\n ip access-group edge_v4 in\n!\n" +
+			"interface GigabitEthernet0/2\n ip access-group edge_v4 in\n!\ncontrol-plane\n service-policy input CONTROLPLANE-POLICY\n!\n" +
+			"ipv6 access-list edge_v6\n deny ipv6 any any\n",
+		"us-mtv-eos1": "ip access-list edge_v4\n   10 permit tcp any any eq ssh\n   20 permit icmp any any\n   30 deny ip any any\n!\n" +
+			"interface Ethernet1\n   ip access-group edge_v4 in\n",
+		"us-mtv-eos2": "ip access-list edge_v4\n   10 deny ip any any\n!\ninterface Ethernet1\n   ip access-group edge_v4 in\n",
+		"us-mtv-eos3": "ip access-list edge_v4\n   10 deny ip any any\n",
+		"us-mtv-eos4": "ip access-list edge_v4\n   permit tcp any any eq ssh\n   permit icmp any any\n   deny ip any any\n!\n" +
+			"interface Ethernet1\n   ip access-group edge_v4 in\n",
+		"us-mtv-mx4":  "interfaces {\n}\n",
+	}
+	input := []*spb.ACLPushItem{
+		switchPushItem("cisco", "us-mtv-isr1", "ios.acl.edge_v4", 4,
+			"no ip access-list extended edge_v4\nip access-list extended edge_v4\n permit tcp any any eq 22\n deny ip any any\n permit udp any any eq 53\nexit\n", false,
+			"GigabitEthernet0/1", "in", "controlplane", "in"),
+		switchPushItem("cisco", "us-mtv-isr1", "ios.acl.edge_v6", 6,
+			"no ipv6 access-list edge_v6\nipv6 access-list edge_v6\n deny ipv6 any any\nexit\n", false,
+			"GigabitEthernet0/1", "out"),
+		switchPushItem("arista", "us-mtv-eos1", "edge_v4", 4,
+			"no ip access-list edge_v4\nip access-list edge_v4\n   10 permit tcp any any eq ssh\n   30 deny ip any any\nexit\n", false,
+			"Ethernet1", "in", "Ethernet2", "in"),
+		switchPushItem("arista", "us-mtv-eos2", "edge_v4", 4,
+			"no ip access-list edge_v4\nip access-list edge_v4\n   10 deny ip any any\nexit\n", false,
+			"Ethernet1", "in"),
+		switchPushItem("arista", "us-mtv-eos3", "edge_v4", 4,
+			"no ip access-list edge_v4\nip access-list edge_v4\n   10 permit tcp any any eq ssh\n   20 deny ip any any\nexit\n", false,
+			"Ethernet1", "in"),
+		switchPushItem("arista", "us-mtv-eos4", "edge_v4", 4,
+			"no ip access-list edge_v4\nip access-list edge_v4\n   permit tcp any any eq ssh\n   deny ip any any\nexit\n", false,
+			"Ethernet1", "in"),
+		switchPushItem("juniper", "us-mtv-mx4", "edge_v4", 4, "firewall {\n}\n", false),
+	}
+	res, err := PreparePushWithOptions(input, PushOptions{Running: running})
+	if err != nil {
+		t.Fatalf("PreparePush error: %v", err)
+	}
+	for _, d := range res.Devices {
+		if d.Errors != nil {
+			t.Errorf("PreparePush errors for device %s: %v", d.Device, d.Errors)
 		}
-		// read config from test file
-		for dev := range acls[0] {
-			wantCanary[dev] = getConfigFromFile(dev, t)
+		if d.Incremental != (d.Vendor != "juniper") {
+			t.Errorf("PreparePush incremental for device %s: got %t", d.Device, d.Incremental)
 		}
-		for dev := range acls[1] {
-			wantGlobal[dev] = getConfigFromFile(dev, t)
+		if !d.Incremental {
+			continue
 		}
-		if !reflect.DeepEqual(acls[0], wantCanary) {
-      t.Errorf("Canary acl mismatch for test: %s, got: %v, want: %v", test.name, acls[0], wantCanary)
+		if want := getConfigFromFile(d.Device+".incremental", t); d.Config != want {
+			t.Errorf("incremental config mismatch for device: %s, got: %q, want: %q", d.Device, d.Config, want)
+		}
+		if want := getConfigFromFile(d.Device+".diff", t); d.Diff != want {
+			t.Errorf("diff mismatch for device: %s, got: %q, want: %q", d.Device, d.Diff, want)
+		}
+		if want := getConfigFromFile(d.Device+".incremental.rollback", t); d.Rollback != want {
+			t.Errorf("incremental rollback mismatch for device: %s, got: %q, want: %q", d.Device, d.Rollback, want)
+		}
+	}
+
+	// data without the acl definition fails the incremental config
+	input = []*spb.ACLPushItem{switchPushItem("arista", "us-mtv-eos3", "edge_v4", 4, "exit\n", false, "Ethernet1", "in")}
+	res, err = PreparePushWithOptions(input, PushOptions{Running: running})
+	if err != nil {
+		t.Fatalf("PreparePush error: %v", err)
+	}
+	if d := res.Device("us-mtv-eos3"); d.Errors == nil || d.Incremental {
+		t.Errorf("PreparePush of acl without definition: got errors %v, incremental %t", d.Errors, d.Incremental)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	a := strings.Split("a b c d e f g h i j k l m", " ")
+	b := strings.Split("a b x d e f g h i j k m n", " ")
+	want := "--- a\n+++ b\n@@ -1,6 +1,6 @@\n a\n b\n-c\n+x\n d\n e\n f\n@@ -9,5 +9,5 @@\n i\n j\n k\n-l\n m\n+n\n"
+	if got := unifiedDiff("a", "b", a, b); got != want {
+		t.Errorf("unifiedDiff: got %q, want %q", got, want)
+	}
+	if got := unifiedDiff("a", "b", a, a); got != "" {
+		t.Errorf("unifiedDiff of equal lines: got %q, want empty", got)
+	}
+}
+
+func TestParseACLs(t *testing.T) {
+	data := "no ip access-list extended edge_v4\nip access-list extended edge_v4\n" +
+		" 10 permit tcp 10.0.0.0 0.0.0.255 eq www 443 host 192.168.0.1 range 1024 2048 established\n" +
+		" 20 remark implicit deny\nexit\n" +
+		"ipv6 access-list edge_v6\n sequence 10 permit udp 2001:db8::/32 any eq domain\n"
+	acls := ParseACLs(data)
+	if len(acls) != 2 {
+		t.Fatalf("ParseACLs: got %d acls, want 2", len(acls))
+	}
+	e := acls[0].Entries[0]
+	if e.Err != nil || e.Seq != 10 || e.Action != "permit" || e.Protocol != "tcp" ||
+		e.Src.IP.String() != "10.0.0.0" || e.Src.Wildcard.String() != "0.0.0.255" ||
+		!reflect.DeepEqual(e.SrcPort, &PortMatch{"eq", []string{"www", "443"}, []int{80, 443}}) ||
+		e.Dst.Wildcard.String() != "0.0.0.0" ||
+		!reflect.DeepEqual(e.DstPort, &PortMatch{"range", []string{"1024", "2048"}, []int{1024, 2048}}) ||
+		!reflect.DeepEqual(e.Options, []string{"established"}) {
+		t.Errorf("ParseACLs entry: got %+v", e)
+	}
+	if r := acls[0].Entries[1]; r.Seq != 20 || r.Remark != "implicit deny" {
+		t.Errorf("ParseACLs remark: got %+v", r)
+	}
+	e = acls[1].Entries[0]
+	if e.Err != nil || e.Seq != 10 || e.Src.Wildcard.String() != "::ffff:ffff:ffff:ffff:ffff:ffff" || !e.Dst.Any() || e.Line != 7 {
+		t.Errorf("ParseACLs IPv6 entry: got %+v", e)
+	}
+}
+
+func TestLintData(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries string
+		want    []string
+	}{
+		{name: "clean", entries: " permit tcp any host 10.0.0.1 eq 22\n deny ip any any\n"},
+		{name: "subnet_mask", entries: " permit ip 10.0.0.0 255.255.255.0 any\n deny ip any any\n",
+			want: []string{"line 3: error: wildcard mask 255.255.255.0 looks like a subnet mask"}},
+		{name: "host_bits", entries: " permit ip 10.0.0.1 0.0.0.255 any\n deny ip any any\n",
+			want: []string{"line 3: warning: address 10.0.0.1 has bits set under wildcard mask 0.0.0.255"}},
+		{name: "unknown_protocol_port", entries: " permit tcpp any any\n permit udp any any eq nosuch\n deny ip any any\n",
+			want: []string{"line 3: error: unknown protocol \"tcpp\"", "line 4: error: unknown port \"nosuch\""}},
+		{name: "duplicate_seq", entries: " 10 permit ip any any\n 10 deny ip any any\n",
+			want: []string{"line 4: error: duplicate sequence number 10 of line 3"}},
+		{name: "implicit_deny", entries: " permit ip any any\n",
+			want: []string{"line 2: warning: no explicit deny any any at the end and no implicit deny remark"}},
+		{name: "implicit_deny_remark", entries: " remark implicit deny\n permit ip any any\n"},
+		{name: "syntax", entries: " permit ip 10.0.0.0 any\n deny ip any any\n",
+			want: []string{"line 3: error: source: invalid address 10.0.0.0 any"}},
+	}
+	for _, test := range tests {
+		var got []string
+		for _, f := range LintData("no ip access-list extended a\nip access-list extended a\n" + test.entries) {
+			got = append(got, strings.TrimPrefix(f.String(), "acl a "))
 		}
-		if !reflect.DeepEqual(acls[1], wantGlobal) {
-      t.Errorf("Global acl mismatch for test: %s, got: %v, want: %v", test.name, acls[1], wantGlobal)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("LintData for test %s: got %q, want %q", test.name, got, test.want)
 		}
 	}
+}
+
+func TestPreparePushLint(t *testing.T) {
+	input := []*spb.ACLPushItem{
+		switchPushItem("cisco", "us-mtv-isr2", "ios.acl.edge_v4", 4,
+			"ip access-list extended edge_v4\n permit ip 10.0.0.0 255.0.0.0 any\n deny ip any any\n", false,
+			"GigabitEthernet0/1", "in"),
+		switchPushItem("cisco", "us-mtv-isr3", "ios.acl.edge_v4", 4,
+			"ip access-list extended edge_v4\n permit ip any any\n", false,
+			"GigabitEthernet0/1", "in"),
+	}
+	res, err := PreparePushWithOptions(input, PushOptions{Lint: true})
+	if err != nil {
+		t.Fatalf("PreparePush error: %v", err)
+	}
+	if d := res.Device("us-mtv-isr2"); len(d.Errors) != 1 || len(d.Lint) != 1 || d.Config != "" {
+		t.Errorf("PreparePush with lint errors: got %+v, want one lint error and no config", d)
+	}
+	if d := res.Device("us-mtv-isr3"); d.Errors != nil || len(d.Lint) != 1 || d.Config == "" {
+		t.Errorf("PreparePush with lint warnings: got %+v, want one lint warning and config", d)
+	}
+}
 
+func TestAnalyzePush(t *testing.T) {
+	v4 := "ip access-list extended edge_v4\n" +
+		" permit tcp 10.0.0.0 0.255.255.255 any eq 22\n" + // line 2
+		" permit tcp 10.1.0.0 0.0.255.255 any eq 22\n" + // line 3, redundant by 2
+		" deny tcp 10.2.0.0 0.0.255.255 any range 20 23\n" + // line 4, redundant by 9
+		" deny ip host 10.3.0.1 any\n" + // line 5, redundant by 9
+		" deny udp any any eq 53 log\n" + // line 6
+		" permit udp 10.0.0.0 0.0.0.255 any eq domain\n" + // line 7, shadowed by 6
+		" deny tcp any any eq telnet\n" + // line 8, redundant by 9
+		" deny ip any any\n"
+	v6 := "ipv6 access-list edge_v6\n" +
+		" permit ipv6 2001:db8::/32 any\n" +
+		" permit tcp 2001:db8:1::/48 any eq 443\n" + // line 3, redundant by 2
+		" deny tcp any any eq 22\n" + // line 4, overlaps 2
+		" deny icmp any any\n" + // line 5, overlaps 2
+		" permit 58 2001:db9::/48 any\n" + // line 6, shadowed by 5
+		" permit 1 2001:db9::/48 any\n" + // line 7
+		" remark implicit deny\n"
+	input := []*spb.ACLPushItem{
+		switchPushItem("cisco", "us-mtv-isr1", "ios.acl.edge_v4", 4, v4, false),
+		switchPushItem("cisco", "us-mtv-isr2", "ios.acl.edge_v4", 4, v4, false),
+		switchPushItem("cisco", "us-mtv-isr1", "ios.acl.edge_v6", 6, v6, false),
+		switchPushItem("juniper", "us-mtv-mx1", "edge_v4", 4, "firewall {\n}\n", false),
+	}
+	want := []string{
+		"acl edge_v4 (ipv4): 5 dead entries, 0 overlaps\n" +
+			"  line 3: redundant by line 2: permit tcp 10.1.0.0 0.0.255.255 any eq 22\n" +
+			"  line 4: redundant by line 9: deny tcp 10.2.0.0 0.0.255.255 any range 20 23\n" +
+			"  line 5: redundant by line 9: deny ip host 10.3.0.1 any\n" +
+			"  line 7: shadowed by line 6: permit udp 10.0.0.0 0.0.0.255 any eq domain\n" +
+			"  line 8: redundant by line 9: deny tcp any any eq telnet\n",
+		"acl edge_v6 (ipv6): 2 dead entries, 2 overlaps\n" +
+			"  line 3: redundant by line 2: permit tcp 2001:db8:1::/48 any eq 443\n" +
+			"  line 4: overlap by line 2: deny tcp any any eq 22\n" +
+			"  line 5: overlap by line 2: deny icmp any any\n" +
+			"  line 6: shadowed by line 5: permit 58 2001:db9::/48 any\n",
+	}
+	var got []string
+	for _, r := range AnalyzePush(input) {
+		got = append(got, r.String())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("AnalyzePush: got %q, want %q", got, want)
+	}
 }
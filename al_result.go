@@ -0,0 +1,119 @@
+package render
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+
+	spb ".../proto/stratus_proto"
+)
+
+// PushResult is the rendered config of the devices of a push.
+type PushResult struct {
+	// Plan is the waves the devices are pushed in.
+	Plan *RolloutPlan
+	// Devices holds the devices ordered by wave, then name.
+	Devices []*DeviceResult
+	// Errors holds an *ItemError for every problem of the skipped push
+	// items.
+	Errors []error
+}
+
+// DeviceResult is the rendered config of a device.
+type DeviceResult struct {
+	Device string
+	Vendor string
+	// Wave is the index of the wave of the device in the plan.
+	Wave   int
+	Config string
+	// ACLNames are the device names of the acls of the config.
+	ACLNames []string
+	// SourceACLNames are the acl names of the push items of the device,
+	// which ACLNames normalize for the vendor.
+	SourceACLNames []string
+	// Interfaces are the interfaces the acls are bound to, sorted.
+	Interfaces []string
+	// Incremental is set if the config changes the running config of the
+	// device, which Diff shows as unified diff.
+	Incremental bool
+	Diff        string
+	// Rollback is the config to be pushed if the push goes wrong. It removes
+	// the bindings of the acls of the config, or reverts the changes of an
+	// incremental config to the running config. It is empty if the config
+	// is.
+	Rollback string
+	// Hash is the hex sha256 of the config.
+	Hash string
+	// Lint holds the lint findings of the acls if PushOptions.Lint is set.
+	Lint []*LintFinding
+	// Errors holds the problems rendering the config, in which case the
+	// config is empty.
+	Errors []error
+}
+
+// sourceACLNames maps device names to the acl names of their push items.
+func sourceACLNames(pushPb []*spb.ACLPushItem) map[string][]string {
+	names := make(map[string][]string)
+	for _, item := range pushPb {
+		for _, ep := range item.GetEnforcePoint() {
+			names[ep.GetDeviceName()] = append(names[ep.GetDeviceName()], item.GetAclName())
+		}
+	}
+	return names
+}
+
+// newDeviceResult returns the result of the device without config.
+func newDeviceResult(device, vendor string, wave int, acls []*acl) *DeviceResult {
+	d := &DeviceResult{Device: device, Vendor: vendor, Wave: wave}
+	intfs := make(map[string]bool)
+	for _, a := range acls {
+		d.ACLNames = append(d.ACLNames, a.Name)
+		for _, id := range a.IntfDirection {
+			if !intfs[id.Intf] {
+				intfs[id.Intf] = true
+				d.Interfaces = append(d.Interfaces, id.Intf)
+			}
+		}
+	}
+	sort.Strings(d.Interfaces)
+	return d
+}
+
+// setConfig sets the config and its hash.
+func (d *DeviceResult) setConfig(config string) {
+	sum := sha256.Sum256([]byte(config))
+	d.Config = config
+	d.Hash = hex.EncodeToString(sum[:])
+}
+
+// Device returns the result of the device, or nil.
+func (r *PushResult) Device(device string) *DeviceResult {
+	for _, d := range r.Devices {
+		if d.Device == device {
+			return d
+		}
+	}
+	return nil
+}
+
+// Wave returns the results of the devices of the wave.
+func (r *PushResult) Wave(wave int) []*DeviceResult {
+	var devices []*DeviceResult
+	for _, d := range r.Devices {
+		if d.Wave == wave {
+			devices = append(devices, d)
+		}
+	}
+	return devices
+}
+
+// Failed returns the results of the devices with errors.
+func (r *PushResult) Failed() []*DeviceResult {
+	var devices []*DeviceResult
+	for _, d := range r.Devices {
+		if len(d.Errors) > 0 {
+			devices = append(devices, d)
+		}
+	}
+	return devices
+}
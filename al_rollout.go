@@ -0,0 +1,238 @@
+package render
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+
+	spb ".../proto/stratus_proto"
+)
+
+// regionTag is the tag type of the region of an enforce point.
+const regionTag = "Region"
+
+// RolloutTag is a tag type and value of enforce points.
+type RolloutTag struct {
+	Type  string
+	Value string
+}
+
+// defaultCanaryTags are the canary tags of rules without CanaryTags.
+var defaultCanaryTags = []RolloutTag{{"Canary", "True"}}
+
+// RolloutRules decide the waves a push is rolled out in. The zero value
+// rolls out the devices tagged Canary=True, then all other devices.
+type RolloutRules struct {
+	// CanaryTags select the devices of the first waves, a device with any of
+	// the tags on any enforce point is a canary. Canary=True if empty.
+	CanaryTags []RolloutTag
+	// RegionOrder splits the other devices into waves by their Region tag,
+	// in this order. Regions not listed follow in alphabetical order, then
+	// devices without a Region tag. The devices are not split if empty.
+	RegionOrder []string
+	// BatchPercents split the devices of every region into batches at the
+	// cumulative percentages, e.g. 10, 50, 100. A device is in the first
+	// batch whose percentage exceeds the hash of its name modulo 100, so it
+	// stays in its batch as others are added. Batches of few devices follow
+	// the percentages loosely.
+	BatchPercents []int
+	// MaxDevices bounds the number of devices of a wave if positive.
+	MaxDevices int
+}
+
+// Wave is a set of devices pushed to together.
+type Wave struct {
+	// Name describes the devices of the wave, e.g. "canary" or "AMER 2/3".
+	Name    string
+	Devices []string
+}
+
+// RolloutPlan is the ordered waves of a push. Every device is in one wave.
+type RolloutPlan struct {
+	Waves []*Wave
+}
+
+// WaveOf returns the index of the wave of the device, or -1.
+func (p *RolloutPlan) WaveOf(device string) int {
+	for i, w := range p.Waves {
+		for _, d := range w.Devices {
+			if d == device {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// Validate checks the rules.
+func (r *RolloutRules) Validate() error {
+	last := 0
+	for _, p := range r.BatchPercents {
+		if p <= last || p > 100 {
+			return fmt.Errorf("batch percentages %v must increase up to 100", r.BatchPercents)
+		}
+		last = p
+	}
+	if len(r.BatchPercents) > 0 && last != 100 {
+		return fmt.Errorf("batch percentages %v must end at 100", r.BatchPercents)
+	}
+	if r.MaxDevices < 0 {
+		return fmt.Errorf("negative max devices %d", r.MaxDevices)
+	}
+	return nil
+}
+
+// isCanary reports whether the tag is one of the canary tags of the rules.
+func (r *RolloutRules) isCanary(tag *spb.ACLEnforcementPoint_Tag) bool {
+	canaryTags := r.CanaryTags
+	if len(canaryTags) == 0 {
+		canaryTags = defaultCanaryTags
+	}
+	for _, t := range canaryTags {
+		if tag.GetType() == t.Type && tag.GetValue() == t.Value {
+			return true
+		}
+	}
+	return false
+}
+
+// PlanRollout orders the devices of the push items into waves by the rules.
+// A device's region is the Region tag of its first enforce point with one.
+func PlanRollout(pushPb []*spb.ACLPushItem, rules *RolloutRules) (*RolloutPlan, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+	canary := make(map[string]bool)
+	region := make(map[string]string)
+	seen := make(map[string]bool)
+	var devices []string
+	for _, item := range pushPb {
+		for _, ep := range item.GetEnforcePoint() {
+			dev := ep.GetDeviceName()
+			if dev == "" {
+				continue
+			}
+			if !seen[dev] {
+				seen[dev] = true
+				devices = append(devices, dev)
+			}
+			for _, tag := range ep.Tags {
+				if rules.isCanary(tag) {
+					canary[dev] = true
+				}
+				if _, ok := region[dev]; !ok && tag.GetType() == regionTag {
+					region[dev] = tag.GetValue()
+				}
+			}
+		}
+	}
+
+	// group devices, canaries first, then by region
+	var canaries []string
+	groups := make(map[string][]string)
+	for _, dev := range devices {
+		if canary[dev] {
+			canaries = append(canaries, dev)
+			continue
+		}
+		if len(rules.RegionOrder) == 0 {
+			groups[""] = append(groups[""], dev)
+			continue
+		}
+		groups[region[dev]] = append(groups[region[dev]], dev)
+	}
+
+	plan := &RolloutPlan{}
+	plan.add("canary", canaries, nil, rules.MaxDevices)
+	if len(rules.RegionOrder) == 0 {
+		plan.add("global", groups[""], rules.BatchPercents, rules.MaxDevices)
+		return plan, nil
+	}
+	for _, r := range regionOrder(rules.RegionOrder, groups) {
+		name := r
+		if name == "" {
+			name = "no region"
+		}
+		plan.add(name, groups[r], rules.BatchPercents, rules.MaxDevices)
+	}
+	return plan, nil
+}
+
+// regionOrder returns the regions of groups, the ordered ones first, then the
+// others alphabetically, then the empty region.
+func regionOrder(ordered []string, groups map[string][]string) []string {
+	var regions []string
+	listed := make(map[string]bool)
+	for _, r := range ordered {
+		if !listed[r] && len(groups[r]) > 0 {
+			regions = append(regions, r)
+		}
+		listed[r] = true
+	}
+	var others []string
+	for r := range groups {
+		if !listed[r] && r != "" {
+			others = append(others, r)
+		}
+	}
+	sort.Strings(others)
+	regions = append(regions, others...)
+	if !listed[""] && len(groups[""]) > 0 {
+		regions = append(regions, "")
+	}
+	return regions
+}
+
+// add appends the waves of the devices, split into batches by the hash of
+// their name at the cumulative percentages and into waves of at most
+// maxDevices.
+func (p *RolloutPlan) add(name string, devices []string, percents []int, maxDevices int) {
+	if len(devices) == 0 {
+		return
+	}
+	if len(percents) == 0 {
+		percents = []int{100}
+	}
+	byBatch := make([][]string, len(percents))
+	for _, dev := range devices {
+		bucket := int(deviceHash(dev) % 100)
+		for i, pct := range percents {
+			if bucket < pct {
+				byBatch[i] = append(byBatch[i], dev)
+				break
+			}
+		}
+	}
+	var batches [][]string
+	for _, b := range byBatch {
+		if len(b) > 0 {
+			sort.Strings(b)
+			batches = append(batches, b)
+		}
+	}
+	var waves [][]string
+	for _, b := range batches {
+		for maxDevices > 0 && len(b) > maxDevices {
+			waves = append(waves, b[:maxDevices])
+			b = b[maxDevices:]
+		}
+		waves = append(waves, b)
+	}
+	for i, w := range waves {
+		waveName := name
+		if len(waves) > 1 {
+			waveName = fmt.Sprintf("%s %d/%d", name, i+1, len(waves))
+		}
+		devs := append([]string(nil), w...)
+		sort.Strings(devs)
+		p.Waves = append(p.Waves, &Wave{waveName, devs})
+	}
+}
+
+// deviceHash returns the fnv hash of the device name, which assigns devices
+// to batches.
+func deviceHash(device string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(device))
+	return h.Sum64()
+}
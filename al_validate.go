@@ -0,0 +1,182 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	spb ".../proto/stratus_proto"
+)
+
+// ValidationPolicy decides what PreparePush does with invalid push items.
+type ValidationPolicy int
+
+const (
+	// RejectInvalid renders no config if any push item is invalid.
+	RejectInvalid ValidationPolicy = iota
+	// SkipInvalid renders the valid push items, leaving out invalid items
+	// and the invalid enforce points of valid items.
+	SkipInvalid
+)
+
+// ItemError is a problem of a push item, or of one of its enforce points.
+type ItemError struct {
+	// Item is the index of the push item.
+	Item    int
+	ACLName string
+	// EnforcePoint is the index of the enforce point in the item, or -1 if
+	// the problem is of the item.
+	EnforcePoint int
+	Device       string
+	Msg          string
+}
+
+func (e *ItemError) Error() string {
+	if e.EnforcePoint < 0 {
+		return fmt.Sprintf("push item %d (acl %q): %s", e.Item, e.ACLName, e.Msg)
+	}
+	return fmt.Sprintf("push item %d (acl %q) enforce point %d (device %q): %s",
+		e.Item, e.ACLName, e.EnforcePoint, e.Device, e.Msg)
+}
+
+// ValidationReport lists the problems of push items.
+type ValidationReport struct {
+	Errors []*ItemError
+}
+
+func (r *ValidationReport) Error() string {
+	var msgs []string
+	for _, e := range r.Errors {
+		msgs = append(msgs, e.Error())
+	}
+	return fmt.Sprintf("%d invalid push items: %s", len(r.Errors), strings.Join(msgs, "; "))
+}
+
+// Valid reports whether the push items have no problems.
+func (r *ValidationReport) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+// invalid returns the indexes of the invalid items, and of the invalid
+// enforce points keyed on item index.
+func (r *ValidationReport) invalid() (map[int]bool, map[int]map[int]bool) {
+	items := make(map[int]bool)
+	points := make(map[int]map[int]bool)
+	for _, e := range r.Errors {
+		if e.EnforcePoint < 0 {
+			items[e.Item] = true
+			continue
+		}
+		if points[e.Item] == nil {
+			points[e.Item] = make(map[int]bool)
+		}
+		points[e.Item][e.EnforcePoint] = true
+	}
+	return items, points
+}
+
+// validDirections are the directions of enforcement units.
+var validDirections = map[string]bool{"in": true, "out": true}
+
+// ValidatePush checks the push items for the fields loadACL requires. The
+// ip version is required of items with enforcement units.
+func ValidatePush(pushPb []*spb.ACLPushItem) *ValidationReport {
+	r := &ValidationReport{}
+	for i, item := range pushPb {
+		if item == nil {
+			r.Errors = append(r.Errors, &ItemError{Item: i, EnforcePoint: -1, Msg: "nil push item"})
+			continue
+		}
+		itemErr := func(format string, a ...interface{}) {
+			r.Errors = append(r.Errors, &ItemError{Item: i, ACLName: item.GetAclName(), EnforcePoint: -1,
+				Msg: fmt.Sprintf(format, a...)})
+		}
+		if item.GetAclName() == "" {
+			itemErr("missing acl name")
+		}
+		if item.GetVendor() == "" {
+			itemErr("missing vendor")
+		}
+		if item.Data == nil {
+			itemErr("missing data")
+		}
+		if item.IpVersion != nil && item.GetIpVersion() != 4 && item.GetIpVersion() != 6 {
+			itemErr("invalid ip version %d", item.GetIpVersion())
+		}
+		if len(item.EnforcePoint) == 0 {
+			itemErr("no enforce point")
+		}
+
+		for j, ep := range item.EnforcePoint {
+			pointErr := func(format string, a ...interface{}) {
+				r.Errors = append(r.Errors, &ItemError{Item: i, ACLName: item.GetAclName(), EnforcePoint: j,
+					Device: ep.GetDeviceName(), Msg: fmt.Sprintf(format, a...)})
+			}
+			if ep == nil {
+				pointErr("nil enforce point")
+				continue
+			}
+			if ep.GetDeviceName() == "" {
+				pointErr("missing device name")
+			}
+			for k, tag := range ep.Tags {
+				if tag == nil || tag.Type == nil || tag.Value == nil {
+					pointErr("tag %d: missing type or value", k)
+				}
+			}
+			if len(ep.Units) > 0 && item.IpVersion == nil {
+				pointErr("missing ip version of enforcement units")
+			}
+			for k, unit := range ep.Units {
+				if unit == nil {
+					pointErr("unit %d: nil unit", k)
+					continue
+				}
+				if !validIntfName(unit.GetName()) {
+					pointErr("unit %d: invalid interface name %q", k, unit.GetName())
+				}
+				if !validDirections[unit.GetDirection()] {
+					pointErr("unit %d: invalid direction %q of %s", k, unit.GetDirection(), unit.GetName())
+				}
+			}
+		}
+	}
+	return r
+}
+
+// validIntfName reports whether name is a non-empty interface name without
+// spaces or control characters, which would break the config lines.
+func validIntfName(name string) bool {
+	return name != "" && strings.IndexFunc(name, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsControl(r)
+	}) < 0
+}
+
+// validItems returns the push items without the invalid items and enforce
+// points of the report.
+func validItems(pushPb []*spb.ACLPushItem, r *ValidationReport) []*spb.ACLPushItem {
+	if r.Valid() {
+		return pushPb
+	}
+	badItems, badPoints := r.invalid()
+	var items []*spb.ACLPushItem
+	for i, item := range pushPb {
+		if badItems[i] {
+			continue
+		}
+		if len(badPoints[i]) == 0 {
+			items = append(items, item)
+			continue
+		}
+		valid := &spb.ACLPushItem{AclName: item.AclName, IpVersion: item.IpVersion, Vendor: item.Vendor, Data: item.Data}
+		for j, ep := range item.EnforcePoint {
+			if !badPoints[i][j] {
+				valid.EnforcePoint = append(valid.EnforcePoint, ep)
+			}
+		}
+		if len(valid.EnforcePoint) > 0 {
+			items = append(items, valid)
+		}
+	}
+	return items
+}
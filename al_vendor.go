@@ -0,0 +1,193 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// VendorRenderer renders the acls of a device of a vendor to device config.
+type VendorRenderer interface {
+	// NormalizeName returns the name of the acl on the device for the acl
+	// name of the push item.
+	NormalizeName(aclName string) string
+	// TemplateName returns the name of the acl template of the vendor, or ""
+	// if the vendor renders without a template.
+	TemplateName() string
+	// Render returns the config of the acls of a device. tmpl is the loaded
+	// template named by TemplateName, or nil.
+	Render(tmpl *template.Template, acls []*acl) (string, error)
+	// Rollback returns the config removing the interface and control plane
+	// bindings of the acls of a device.
+	Rollback(tmpl *template.Template, acls []*acl) (string, error)
+}
+
+// vendorRenderers maps the vendor of push items to its renderer.
+var vendorRenderers = map[string]VendorRenderer{
+	"arista":  ciscoRenderer{"arista_acl", "access-group"},
+	"cisco":   ciscoRenderer{"cisco_acl", "traffic-filter"},
+	"juniper": juniperRenderer{},
+	"nxos":    ciscoRenderer{"nxos_acl", "traffic-filter"},
+}
+
+// vendorRenderer returns the renderer of the vendor.
+func vendorRenderer(vendor string) (VendorRenderer, error) {
+	r, ok := vendorRenderers[vendor]
+	if !ok {
+		return nil, fmt.Errorf("unknown vendor %q", vendor)
+	}
+	return r, nil
+}
+
+// normalizeName returns the device acl name of the acl name for the vendor,
+// or the acl name unchanged for an unknown vendor.
+func normalizeName(vendor, aclName string) string {
+	if r, err := vendorRenderer(vendor); err == nil {
+		return r.NormalizeName(aclName)
+	}
+	return aclName
+}
+
+// ciscoRenderer renders acls with the interface and control plane bindings
+// of a template of the Cisco style command line, which Arista EOS and Cisco
+// NX-OS share with IOS.
+type ciscoRenderer struct {
+	template string
+	// ipv6Filter is the keyword binding IPv6 acls to interfaces after ipv6.
+	ipv6Filter string
+}
+
+// NormalizeName returns the third dot separated field of the acl name.
+func (ciscoRenderer) NormalizeName(aclName string) string {
+	if fields := strings.Split(aclName, "."); len(fields) > 2 {
+		return fields[2]
+	}
+	return aclName
+}
+
+func (r ciscoRenderer) TemplateName() string {
+	return r.template
+}
+
+// Render executes the layout template for every acl and drops the exit
+// lines of the acl data.
+func (ciscoRenderer) Render(tmpl *template.Template, acls []*acl) (string, error) {
+	config, err := executeACLs(tmpl, "layout", acls)
+	return strings.Replace(config, "\nexit", "", -1), err
+}
+
+// Rollback executes the rollback template for every acl.
+func (ciscoRenderer) Rollback(tmpl *template.Template, acls []*acl) (string, error) {
+	return executeACLs(tmpl, "rollback", acls)
+}
+
+// executeACLs executes the named template for every acl.
+func executeACLs(tmpl *template.Template, name string, acls []*acl) (string, error) {
+	b := bytes.NewBuffer([]byte{})
+	for _, data := range acls {
+		if err := tmpl.ExecuteTemplate(b, name, data); err != nil {
+			return "", err
+		}
+	}
+	return b.String(), nil
+}
+
+// junosControlPlane is the interface which filters of the routing engine are
+// bound to on Junos.
+const junosControlPlane = "lo0"
+
+// junosFamilies maps ip versions to Junos protocol families.
+var junosFamilies = map[int32]string{4: "inet", 6: "inet6"}
+
+// junosDirections maps unit directions to Junos filter directions.
+var junosDirections = map[string]string{"in": "input", "out": "output"}
+
+// junosACL is the data of the juniper_acl template for an acl.
+type junosACL struct {
+	*acl
+	Family string
+	Units  []*junosUnit
+}
+
+// junosUnit is a logical unit of an interface the filter is bound to.
+type junosUnit struct {
+	Intf       string
+	Unit       string
+	Directions []string
+}
+
+// juniperRenderer renders acls as their data, which holds the firewall
+// stanzas, followed by the interfaces stanzas binding the filters to units.
+type juniperRenderer struct{}
+
+func (juniperRenderer) NormalizeName(aclName string) string {
+	return aclName
+}
+
+func (juniperRenderer) TemplateName() string {
+	return "juniper_acl"
+}
+
+// Render executes the layout template for every acl.
+func (juniperRenderer) Render(tmpl *template.Template, acls []*acl) (string, error) {
+	return executeJunosACLs(tmpl, "layout", acls)
+}
+
+// Rollback executes the rollback template for every acl, which deletes the
+// filter statements of the units.
+func (juniperRenderer) Rollback(tmpl *template.Template, acls []*acl) (string, error) {
+	return executeJunosACLs(tmpl, "rollback", acls)
+}
+
+// executeJunosACLs executes the named template for the units of every acl.
+func executeJunosACLs(tmpl *template.Template, name string, acls []*acl) (string, error) {
+	b := bytes.NewBuffer([]byte{})
+	for _, data := range acls {
+		j, err := newJunosACL(data)
+		if err != nil {
+			return "", err
+		}
+		if err := tmpl.ExecuteTemplate(b, name, j); err != nil {
+			return "", err
+		}
+	}
+	return b.String(), nil
+}
+
+// newJunosACL groups the interface bindings of the acl by unit. Units are
+// named "interface.unit", the unit defaults to 0, and the controlplane unit
+// is unit 0 of lo0.
+func newJunosACL(a *acl) (*junosACL, error) {
+	j := &junosACL{acl: a}
+	if len(a.IntfDirection) == 0 {
+		return j, nil
+	}
+	if j.Family = junosFamilies[a.IPVersion]; j.Family == "" {
+		return nil, fmt.Errorf("acl %s: unknown ip version %d", a.Name, a.IPVersion)
+	}
+	units := make(map[string]*junosUnit)
+	for _, id := range a.IntfDirection {
+		direction := junosDirections[id.Direction]
+		if direction == "" {
+			return nil, fmt.Errorf("acl %s: unknown direction %q of %s", a.Name, id.Direction, id.Intf)
+		}
+		intf, unit := id.Intf, "0"
+		if intf == "controlplane" {
+			intf = junosControlPlane
+		} else if i := strings.LastIndex(intf, "."); i >= 0 {
+			intf, unit = intf[:i], intf[i+1:]
+		}
+		if intf == "" || unit == "" {
+			return nil, fmt.Errorf("acl %s: invalid unit %q", a.Name, id.Intf)
+		}
+		u := units[intf+"."+unit]
+		if u == nil {
+			u = &junosUnit{Intf: intf, Unit: unit}
+			units[intf+"."+unit] = u
+			j.Units = append(j.Units, u)
+		}
+		u.Directions = append(u.Directions, direction)
+	}
+	return j, nil
+}
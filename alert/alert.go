@@ -0,0 +1,257 @@
+// Package alert evaluates alerting rules on audit stats and notifies about
+// the alerts which fire.
+package alert
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	".../go/config"
+	".../go/models"
+)
+
+// RuleType is the condition a rule checks.
+type RuleType string
+
+const (
+	// ErrCountJump fires if ErrCount grew by at least Threshold errors
+	// since the previous snapshot.
+	ErrCountJump RuleType = "err_count_jump"
+	// ErrCountJumpPercent fires if ErrCount grew by at least Threshold
+	// percent since the previous snapshot.
+	ErrCountJumpPercent RuleType = "err_count_jump_percent"
+	// ErrPerAbove fires while ErrPer is above Threshold.
+	ErrPerAbove RuleType = "err_per_above"
+)
+
+// allAudits is the audit name of rules applying to every audit.
+const allAudits = "*"
+
+// Rule is an alerting condition on the stats of one or all audits.
+type Rule struct {
+	AuditName string
+	Type      RuleType
+	Threshold float64
+}
+
+// Alert is a rule firing for an audit on a snapshot.
+type Alert struct {
+	Rule      *Rule   `json:"-"`
+	AuditName string  `json:"audit_name"`
+	Datestamp string  `json:"datestamp"`
+	Value     float64 `json:"value"`
+	Previous  float64 `json:"previous"`
+	Message   string  `json:"message"`
+}
+
+// Key identifies an alert for de-duplication. Jumps are events of a single
+// snapshot, so their key contains the datestamp. ErrPerAbove is a state which
+// lasts over snapshots until it clears.
+func (a *Alert) Key() string {
+	key := fmt.Sprintf("%s:%s:%g", a.Rule.Type, a.AuditName, a.Rule.Threshold)
+	if a.Rule.Type != ErrPerAbove {
+		key += ":" + a.Datestamp
+	}
+	return key
+}
+
+// RulesFromConfig validates the configured rules.
+func RulesFromConfig(cfgRules []*config.AlertRule) ([]*Rule, error) {
+	var rules []*Rule
+	for _, r := range cfgRules {
+		t := RuleType(r.Type)
+		switch t {
+		case ErrCountJump, ErrCountJumpPercent, ErrPerAbove:
+		default:
+			return nil, fmt.Errorf("unknown alert rule type %q", r.Type)
+		}
+		auditName := r.AuditName
+		if auditName == "" {
+			auditName = allAudits
+		}
+		rules = append(rules, &Rule{auditName, t, r.Threshold})
+	}
+	return rules, nil
+}
+
+// appliesTo reports whether the rule is evaluated for auditName.
+func (r *Rule) appliesTo(auditName string) bool {
+	return r.AuditName == allAudits || r.AuditName == auditName
+}
+
+// Evaluate checks rules against the newest snapshot of every audit in stats
+// and returns the alerts which fire.
+func Evaluate(rules []*Rule, stats map[string][]*models.StatsRecord) ([]*Alert, error) {
+	auditNames := make([]string, 0, len(stats))
+	for k := range stats {
+		auditNames = append(auditNames, k)
+	}
+	sort.Strings(auditNames)
+
+	var alerts []*Alert
+	for _, auditName := range auditNames {
+		records := make([]*models.StatsRecord, len(stats[auditName]))
+		copy(records, stats[auditName])
+		if len(records) == 0 {
+			continue
+		}
+		sort.Slice(records, func(i, j int) bool { return records[i].Datestamp < records[j].Datestamp })
+		latest := records[len(records)-1]
+		var previous *models.StatsRecord
+		if len(records) > 1 {
+			previous = records[len(records)-2]
+		}
+		for _, rule := range rules {
+			if !rule.appliesTo(auditName) {
+				continue
+			}
+			a, err := rule.evaluate(latest, previous)
+			if err != nil {
+				return nil, err
+			}
+			if a != nil {
+				alerts = append(alerts, a)
+			}
+		}
+	}
+	return alerts, nil
+}
+
+// evaluate returns an alert if the rule fires for latest, nil otherwise.
+func (r *Rule) evaluate(latest, previous *models.StatsRecord) (*Alert, error) {
+	a := &Alert{Rule: r, AuditName: latest.AuditName, Datestamp: latest.Datestamp}
+	switch r.Type {
+	case ErrPerAbove:
+		errPer, err := strconv.ParseFloat(latest.ErrPer, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ErrPer %q of %s on %s", latest.ErrPer, latest.AuditName, latest.Datestamp)
+		}
+		if errPer <= r.Threshold {
+			return nil, nil
+		}
+		a.Value = errPer
+		a.Message = fmt.Sprintf("%s error percentage %.4f is above %g", a.AuditName, errPer, r.Threshold)
+	case ErrCountJump, ErrCountJumpPercent:
+		if previous == nil {
+			return nil, nil
+		}
+		a.Value = float64(latest.ErrCount)
+		a.Previous = float64(previous.ErrCount)
+		jump := a.Value - a.Previous
+		if r.Type == ErrCountJump {
+			if jump < r.Threshold {
+				return nil, nil
+			}
+			a.Message = fmt.Sprintf("%s error count jumped by %g from %g to %g", a.AuditName, jump, a.Previous, a.Value)
+			break
+		}
+		if a.Previous == 0 {
+			// Any growth from zero errors is an infinite jump.
+			if jump <= 0 {
+				return nil, nil
+			}
+		} else if jump*100/a.Previous < r.Threshold {
+			return nil, nil
+		}
+		a.Message = fmt.Sprintf("%s error count jumped by more than %g%% from %g to %g", a.AuditName, r.Threshold, a.Previous, a.Value)
+	}
+	return a, nil
+}
+
+// StateStore keeps track of the alerts which have been notified.
+type StateStore interface {
+	// FiringAlerts returns the keys of notified alerts which did not clear yet.
+	FiringAlerts() (map[string]bool, error)
+	// SetAlertFiring marks the alert key as notified or cleared.
+	SetAlertFiring(key string, firing bool) error
+}
+
+// memoryState is a StateStore kept in memory.
+type memoryState map[string]bool
+
+// NewMemoryState returns a StateStore which does not outlive the process.
+func NewMemoryState() StateStore {
+	return memoryState{}
+}
+
+// FiringAlerts returns the keys of notified alerts.
+func (s memoryState) FiringAlerts() (map[string]bool, error) {
+	firing := make(map[string]bool, len(s))
+	for k := range s {
+		firing[k] = true
+	}
+	return firing, nil
+}
+
+// SetAlertFiring marks the alert key as notified or cleared.
+func (s memoryState) SetAlertFiring(key string, firing bool) error {
+	if firing {
+		s[key] = true
+	} else {
+		delete(s, key)
+	}
+	return nil
+}
+
+// Dispatcher sends alerts to notifiers once until they clear.
+type Dispatcher struct {
+	Notifiers []Notifier
+	State     StateStore
+}
+
+// Dispatch notifies about alerts which have not been notified before, and
+// clears notified alerts which no longer fire. It returns the alerts sent.
+func (d *Dispatcher) Dispatch(alerts []*Alert) ([]*Alert, error) {
+	firing, err := d.State.FiringAlerts()
+	if err != nil {
+		return nil, err
+	}
+	var fresh []*Alert
+	current := make(map[string]bool)
+	for _, a := range alerts {
+		key := a.Key()
+		current[key] = true
+		if !firing[key] {
+			fresh = append(fresh, a)
+		}
+	}
+	for key := range firing {
+		if !current[key] {
+			if err := d.State.SetAlertFiring(key, false); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(fresh) == 0 {
+		return nil, nil
+	}
+
+	var errs []string
+	sent := false
+	for _, n := range d.Notifiers {
+		if err := n.Notify(fresh); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		sent = true
+	}
+	// Alerts are marked if any notifier succeeded, the others would see
+	// them again on every run otherwise. Alerts nobody was notified about
+	// are retried on the next run.
+	if !sent {
+		if len(errs) > 0 {
+			return nil, fmt.Errorf("notify error: %v", errs)
+		}
+		return nil, nil
+	}
+	for _, a := range fresh {
+		if err := d.State.SetAlertFiring(a.Key(), true); err != nil {
+			return nil, err
+		}
+	}
+	if len(errs) > 0 {
+		return fresh, fmt.Errorf("notify error: %v", errs)
+	}
+	return fresh, nil
+}
@@ -0,0 +1,254 @@
+package alert
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	".../go/config"
+	".../go/mail"
+	".../go/models"
+)
+
+var stats = map[string][]*models.StatsRecord{
+	"dns": {
+		{AuditName: "dns", ErrCount: 25, ErrPer: "0.0250", Datestamp: "2016-05-02"},
+		{AuditName: "dns", ErrCount: 10, ErrPer: "0.0100", Datestamp: "2016-05-01"},
+	},
+	"vlan": {
+		{AuditName: "vlan", ErrCount: 0, ErrPer: "0.0000", Datestamp: "2016-05-01"},
+		{AuditName: "vlan", ErrCount: 3, ErrPer: "0.0600", Datestamp: "2016-05-02"},
+	},
+	"gateway": {
+		{AuditName: "gateway", ErrCount: 7, ErrPer: "0.0010", Datestamp: "2016-05-02"},
+	},
+}
+
+// recorder is a Notifier which keeps the alerts of every notification.
+type recorder struct {
+	calls [][]*Alert
+}
+
+func (r *recorder) Notify(alerts []*Alert) error {
+	r.calls = append(r.calls, alerts)
+	return nil
+}
+
+func alertNames(alerts []*Alert) []string {
+	var names []string
+	for _, a := range alerts {
+		names = append(names, string(a.Rule.Type)+":"+a.AuditName)
+	}
+	return names
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []*config.AlertRule
+		want  []string
+	}{
+		{
+			name:  "err_count_jump",
+			rules: []*config.AlertRule{{Type: "err_count_jump", Threshold: 10}},
+			want:  []string{"err_count_jump:dns"},
+		},
+		{
+			name:  "err_count_jump_percent",
+			rules: []*config.AlertRule{{AuditName: "*", Type: "err_count_jump_percent", Threshold: 100}},
+			want:  []string{"err_count_jump_percent:dns", "err_count_jump_percent:vlan"},
+		},
+		{
+			name:  "err_per_above_single_audit",
+			rules: []*config.AlertRule{{AuditName: "vlan", Type: "err_per_above", Threshold: 0.05}},
+			want:  []string{"err_per_above:vlan"},
+		},
+		{
+			name:  "no_previous_snapshot",
+			rules: []*config.AlertRule{{AuditName: "gateway", Type: "err_count_jump", Threshold: 1}},
+		},
+	}
+
+	for _, test := range tests {
+		rules, err := RulesFromConfig(test.rules)
+		if err != nil {
+			t.Fatalf("RulesFromConfig error for test: %s: %v", test.name, err)
+		}
+		alerts, err := Evaluate(rules, stats)
+		if err != nil {
+			t.Errorf("Evaluate error for test: %s: %v", test.name, err)
+		}
+		if got := alertNames(alerts); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Alert mismatch for test: %s, got: %v, want: %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestRulesFromConfigUnknownType(t *testing.T) {
+	if _, err := RulesFromConfig([]*config.AlertRule{{Type: "err_count_drop"}}); err == nil {
+		t.Errorf("RulesFromConfig accepted unknown rule type")
+	}
+}
+
+func TestDispatchDeduplicates(t *testing.T) {
+	rules, _ := RulesFromConfig([]*config.AlertRule{{AuditName: "vlan", Type: "err_per_above", Threshold: 0.05}})
+	alerts, _ := Evaluate(rules, stats)
+	r := &recorder{}
+	d := &Dispatcher{Notifiers: []Notifier{r}, State: NewMemoryState()}
+
+	for i := 0; i < 2; i++ {
+		if _, err := d.Dispatch(alerts); err != nil {
+			t.Fatalf("Dispatch error: %v", err)
+		}
+	}
+	if len(r.calls) != 1 {
+		t.Errorf("Repeated alert notified %d times, want 1", len(r.calls))
+	}
+
+	// Once the alert clears it fires again.
+	if _, err := d.Dispatch(nil); err != nil {
+		t.Fatalf("Dispatch error: %v", err)
+	}
+	if _, err := d.Dispatch(alerts); err != nil {
+		t.Fatalf("Dispatch error: %v", err)
+	}
+	if len(r.calls) != 2 {
+		t.Errorf("Cleared alert notified %d times, want 2", len(r.calls))
+	}
+}
+
+// failing is a Notifier which always fails.
+type failing struct{}
+
+func (failing) Notify(alerts []*Alert) error {
+	return errors.New("unreachable")
+}
+
+func TestDispatchRetriesFailed(t *testing.T) {
+	rules, _ := RulesFromConfig([]*config.AlertRule{{AuditName: "vlan", Type: "err_per_above", Threshold: 0.05}})
+	alerts, _ := Evaluate(rules, stats)
+	state := NewMemoryState()
+
+	d := &Dispatcher{Notifiers: []Notifier{failing{}}, State: state}
+	if sent, err := d.Dispatch(alerts); err == nil || len(sent) != 0 {
+		t.Errorf("Dispatch with failing notifier, got: %d sent, error: %v", len(sent), err)
+	}
+	if firing, _ := state.FiringAlerts(); len(firing) != 0 {
+		t.Errorf("Alerts marked firing after every notifier failed: %v", firing)
+	}
+
+	r := &recorder{}
+	d.Notifiers = []Notifier{failing{}, r}
+	if sent, err := d.Dispatch(alerts); err == nil || len(sent) != 1 {
+		t.Errorf("Dispatch with one failing notifier, got: %d sent, error: %v", len(sent), err)
+	}
+	if len(r.calls) != 1 {
+		t.Errorf("Retried alert notified %d times, want 1", len(r.calls))
+	}
+	if firing, _ := state.FiringAlerts(); len(firing) != 1 {
+		t.Errorf("Alerts firing after a notifier succeeded: %v, want 1", firing)
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var got webhookPayload
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if err := json.NewDecoder(req.Body).Decode(&got); err != nil {
+			t.Errorf("Invalid webhook body: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	rules, _ := RulesFromConfig([]*config.AlertRule{{Type: "err_count_jump", Threshold: 10}})
+	alerts, _ := Evaluate(rules, stats)
+	n := &WebhookNotifier{URL: ts.URL}
+	if err := n.Notify(alerts); err != nil {
+		t.Fatalf("Notify error: %v", err)
+	}
+	if len(got.Alerts) != 1 || got.Alerts[0].AuditName != "dns" || got.Alerts[0].Value != 25 {
+		t.Errorf("Webhook payload mismatch, got: %+v", got)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	n = &WebhookNotifier{URL: failing.URL}
+	if err := n.Notify(alerts); err == nil {
+		t.Errorf("Notify succeeded on webhook error status")
+	}
+}
+
+// serveSMTP accepts a single SMTP session on l and sends the message data
+// to msgs.
+func serveSMTP(t *testing.T, l net.Listener, msgs chan<- string) {
+	conn, err := l.Accept()
+	if err != nil {
+		t.Errorf("Accept error: %v", err)
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "DATA"):
+			reply("354 go ahead")
+			var data []string
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil || l == ".\r\n" {
+					break
+				}
+				data = append(data, l)
+			}
+			msgs <- strings.Join(data, "")
+			reply("250 ok")
+		case strings.HasPrefix(cmd, "QUIT"):
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func TestEmailNotifier(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen error: %v", err)
+	}
+	defer l.Close()
+	msgs := make(chan string, 1)
+	go serveSMTP(t, l, msgs)
+
+	rules, _ := RulesFromConfig([]*config.AlertRule{{Type: "err_count_jump", Threshold: 10}})
+	alerts, _ := Evaluate(rules, stats)
+	n := &EmailNotifier{
+		Sender: &mail.SMTPSender{Addr: l.Addr().String()},
+		From:   "dragonwell@example.com",
+		To:     []string{"netops@example.com"},
+	}
+	if err := n.Notify(alerts); err != nil {
+		t.Fatalf("Notify error: %v", err)
+	}
+	msg := <-msgs
+	for _, want := range []string{"To: netops@example.com", "dns error count jumped by 15 from 10 to 25"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("Mail missing %q, got: %s", want, msg)
+		}
+	}
+}
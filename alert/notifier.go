@@ -0,0 +1,79 @@
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	".../go/mail"
+)
+
+// Notifier sends alerts to their recipients.
+type Notifier interface {
+	Notify(alerts []*Alert) error
+}
+
+// WebhookNotifier posts alerts as json to an URL.
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+// webhookPayload is the json body posted by WebhookNotifier.
+type webhookPayload struct {
+	Text   string   `json:"text"`
+	Alerts []*Alert `json:"alerts"`
+}
+
+// Notify posts the alerts to the webhook URL.
+func (n *WebhookNotifier) Notify(alerts []*Alert) error {
+	js, err := json.Marshal(&webhookPayload{summary(alerts), alerts})
+	if err != nil {
+		return err
+	}
+	client := n.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Post(n.URL, "application/json", bytes.NewReader(js))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook %s returned %s", n.URL, resp.Status)
+	}
+	return nil
+}
+
+// EmailNotifier mails alerts.
+type EmailNotifier struct {
+	Sender mail.Sender
+	From   string
+	To     []string
+}
+
+// Notify mails the alerts to the recipients.
+func (n *EmailNotifier) Notify(alerts []*Alert) error {
+	subject := fmt.Sprintf("[Dragonwell] %d audit alert(s)", len(alerts))
+	if len(alerts) == 1 {
+		subject = "[Dragonwell] " + alerts[0].Message
+	}
+	return n.Sender.Send(&mail.Message{
+		From:    n.From,
+		To:      n.To,
+		Subject: subject,
+		Text:    summary(alerts),
+	})
+}
+
+// summary returns the alert messages one per line.
+func summary(alerts []*Alert) string {
+	lines := make([]string, 0, len(alerts))
+	for _, a := range alerts {
+		lines = append(lines, fmt.Sprintf("%s: %s", a.Datestamp, a.Message))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,260 @@
+// Package chart renders SVG charts of audit and autofix stats, so that the
+// site and exported reports do not depend on a charting service.
+package chart
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"html/template"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	defaultWidth  = 600
+	defaultHeight = 300
+	marginLeft    = 50
+	marginRight   = 110
+	marginTop     = 20
+	marginBottom  = 30
+	yTicks        = 5
+	xTicks        = 6
+	fontStyle     = `font-family="Tahoma, Arial" font-size="10"`
+)
+
+// Point is a value at a time.
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// Series is a line of a TimeSeries.
+type Series struct {
+	Name   string
+	Color  string
+	Points []Point
+}
+
+// Marker marks an event at a time of a TimeSeries.
+type Marker struct {
+	Time  time.Time
+	Label string
+	// Link is opened when the marker is clicked, if set.
+	Link string
+}
+
+// TimeSeries is a line chart of one or more series over time.
+type TimeSeries struct {
+	Title  string
+	Width  int
+	Height int
+	// Max fixes the top of the y axis, it is derived from the values if 0.
+	Max    float64
+	Series []*Series
+	// Markers are drawn as vertical lines if they are within the time span
+	// of the series.
+	Markers []*Marker
+}
+
+// Slice is a segment of a Pie.
+type Slice struct {
+	Label string
+	Value float64
+	Color string
+}
+
+// Pie is a pie chart.
+type Pie struct {
+	Title  string
+	Width  int
+	Height int
+	Slices []*Slice
+}
+
+// size returns the chart size, or the default size if unset.
+func size(width, height int) (int, int) {
+	if width <= 0 {
+		width = defaultWidth
+	}
+	if height <= 0 {
+		height = defaultHeight
+	}
+	return width, height
+}
+
+// niceMax rounds v up to 1, 2 or 5 times a power of ten.
+func niceMax(v float64) float64 {
+	if v <= 0 {
+		return 1
+	}
+	exp := math.Pow(10, math.Floor(math.Log10(v)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if v <= m*exp {
+			return m * exp
+		}
+	}
+	return 10 * exp
+}
+
+// formatValue formats an axis value without trailing zeros.
+func formatValue(v float64) string {
+	return fmt.Sprintf("%.4g", v)
+}
+
+// svgHeader writes the opening svg element and the title.
+func svgHeader(b *bytes.Buffer, title string, width, height int) {
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, width, height, width, height)
+	b.WriteString("\n")
+	if title != "" {
+		fmt.Fprintf(b, `<text x="%d" y="12" %s font-weight="bold">%s</text>`+"\n", marginLeft, fontStyle, html.EscapeString(title))
+	}
+}
+
+// SVG renders the time series chart.
+func (c *TimeSeries) SVG() template.HTML {
+	width, height := size(c.Width, c.Height)
+	plotW := float64(width - marginLeft - marginRight)
+	plotH := float64(height - marginTop - marginBottom)
+
+	var start, end time.Time
+	yMax := c.Max
+	for _, s := range c.Series {
+		for _, p := range s.Points {
+			if start.IsZero() || p.Time.Before(start) {
+				start = p.Time
+			}
+			if p.Time.After(end) {
+				end = p.Time
+			}
+			if c.Max == 0 && p.Value > yMax {
+				yMax = p.Value
+			}
+		}
+	}
+	if c.Max == 0 {
+		yMax = niceMax(yMax)
+	}
+	span := end.Sub(start)
+	x := func(t time.Time) float64 {
+		if span <= 0 {
+			return marginLeft + plotW/2
+		}
+		return marginLeft + plotW*float64(t.Sub(start))/float64(span)
+	}
+	y := func(v float64) float64 {
+		return marginTop + plotH - plotH*v/yMax
+	}
+
+	b := bytes.NewBuffer([]byte{})
+	svgHeader(b, c.Title, width, height)
+
+	// y axis grid and labels.
+	for i := 0; i <= yTicks; i++ {
+		v := yMax * float64(i) / yTicks
+		fmt.Fprintf(b, `<line x1="%d" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#ddd"/>`+"\n",
+			marginLeft, y(v), marginLeft+plotW, y(v))
+		fmt.Fprintf(b, `<text x="%d" y="%.1f" %s text-anchor="end">%s</text>`+"\n",
+			marginLeft-4, y(v)+3, fontStyle, formatValue(v))
+	}
+	// x axis labels.
+	if !start.IsZero() {
+		ticks := xTicks
+		if span <= 0 {
+			ticks = 0
+		}
+		for i := 0; i <= ticks; i++ {
+			t := start.Add(time.Duration(float64(span) * float64(i) / float64(xTicks)))
+			fmt.Fprintf(b, `<text x="%.1f" y="%d" %s text-anchor="middle">%s</text>`+"\n",
+				x(t), height-marginBottom+14, fontStyle, t.Format("2006-01-02"))
+		}
+	}
+	fmt.Fprintf(b, `<line x1="%d" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#666"/>`+"\n",
+		marginLeft, y(0), marginLeft+plotW, y(0))
+
+	for _, m := range c.Markers {
+		if start.IsZero() || m.Time.Before(start) || m.Time.After(end) {
+			continue
+		}
+		title := html.EscapeString(m.Time.Format("2006-01-02") + " " + m.Label)
+		if m.Link != "" {
+			fmt.Fprintf(b, `<a href="%s" target="_blank">`, html.EscapeString(m.Link))
+		}
+		fmt.Fprintf(b, `<g class="marker"><title>%s</title>`, title)
+		fmt.Fprintf(b, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%.1f" stroke="#777" stroke-dasharray="4,3"/>`,
+			x(m.Time), marginTop, x(m.Time), y(0))
+		fmt.Fprintf(b, `<circle cx="%.1f" cy="%d" r="4" fill="#777"/></g>`, x(m.Time), marginTop)
+		if m.Link != "" {
+			b.WriteString("</a>")
+		}
+		b.WriteString("\n")
+	}
+
+	for i, s := range c.Series {
+		points := make([]Point, len(s.Points))
+		copy(points, s.Points)
+		sort.Slice(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+		color := html.EscapeString(s.Color)
+		coords := bytes.NewBuffer([]byte{})
+		for _, p := range points {
+			fmt.Fprintf(coords, "%.1f,%.1f ", x(p.Time), y(p.Value))
+		}
+		fmt.Fprintf(b, `<polyline fill="none" stroke="%s" stroke-width="2" points="%s"/>`+"\n",
+			color, bytes.TrimSpace(coords.Bytes()))
+		for _, p := range points {
+			fmt.Fprintf(b, `<circle cx="%.1f" cy="%.1f" r="2" fill="%s"><title>%s %s: %s</title></circle>`+"\n",
+				x(p.Time), y(p.Value), color, p.Time.Format("2006-01-02"), html.EscapeString(s.Name), formatValue(p.Value))
+		}
+		// legend.
+		ly := marginTop + 14*i
+		fmt.Fprintf(b, `<rect x="%d" y="%d" width="10" height="10" fill="%s"/>`+"\n", width-marginRight+10, ly, color)
+		fmt.Fprintf(b, `<text x="%d" y="%d" %s>%s</text>`+"\n", width-marginRight+24, ly+9, fontStyle, html.EscapeString(s.Name))
+	}
+	b.WriteString("</svg>\n")
+	return template.HTML(b.String())
+}
+
+// SVG renders the pie chart.
+func (p *Pie) SVG() template.HTML {
+	width, height := size(p.Width, p.Height)
+	r := math.Min(float64(width-marginRight), float64(height-marginTop))/2 - 10
+	cx, cy := r+10, marginTop+r+5
+
+	var total float64
+	for _, s := range p.Slices {
+		if s.Value > 0 {
+			total += s.Value
+		}
+	}
+
+	b := bytes.NewBuffer([]byte{})
+	svgHeader(b, p.Title, width, height)
+	angle := -math.Pi / 2
+	for i, s := range p.Slices {
+		color := html.EscapeString(s.Color)
+		label := html.EscapeString(s.Label)
+		if total > 0 && s.Value > 0 {
+			frac := s.Value / total
+			title := fmt.Sprintf("<title>%s: %s (%.1f%%)</title>", label, formatValue(s.Value), frac*100)
+			if frac >= 1 {
+				fmt.Fprintf(b, `<circle cx="%.1f" cy="%.1f" r="%.1f" fill="%s">%s</circle>`+"\n", cx, cy, r, color, title)
+			} else {
+				next := angle + 2*math.Pi*frac
+				large := 0
+				if frac > 0.5 {
+					large = 1
+				}
+				fmt.Fprintf(b, `<path d="M%.1f,%.1f L%.1f,%.1f A%.1f,%.1f 0 %d,1 %.1f,%.1f Z" fill="%s">%s</path>`+"\n",
+					cx, cy, cx+r*math.Cos(angle), cy+r*math.Sin(angle), r, r, large,
+					cx+r*math.Cos(next), cy+r*math.Sin(next), color, title)
+				angle = next
+			}
+		}
+		ly := marginTop + 14*i
+		fmt.Fprintf(b, `<rect x="%d" y="%d" width="10" height="10" fill="%s"/>`+"\n", width-marginRight+10, ly, color)
+		fmt.Fprintf(b, `<text x="%d" y="%d" %s>%s (%s)</text>`+"\n", width-marginRight+24, ly+9, fontStyle, label, formatValue(s.Value))
+	}
+	b.WriteString("</svg>\n")
+	return template.HTML(b.String())
+}
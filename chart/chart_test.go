@@ -0,0 +1,130 @@
+package chart
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	".../go/models"
+	".../go/slo"
+)
+
+// elements parses svg and counts its elements by name.
+func elements(t *testing.T, svg string) map[string]int {
+	counts := make(map[string]int)
+	d := xml.NewDecoder(strings.NewReader(svg))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return counts
+		}
+		if err != nil {
+			t.Fatalf("Invalid SVG: %v\n%s", err, svg)
+		}
+		if se, ok := tok.(xml.StartElement); ok {
+			counts[se.Name.Local]++
+		}
+	}
+}
+
+func TestStatsCharts(t *testing.T) {
+	records := []*models.StatsRecord{
+		{AuditName: "dns", ErrCount: 25, WarnCount: 3, ErrPer: "0.0250", WarnPer: "0.0030", Datestamp: "2016-05-02"},
+		{AuditName: "dns", ErrCount: 10, WarnCount: 1, ErrPer: "0.0100", WarnPer: "0.0010", Datestamp: "2016-05-01"},
+		{AuditName: "dns", ErrCount: 12, WarnCount: 0, ErrPer: "0.0120", WarnPer: "0", Datestamp: "bad date"},
+	}
+	tests := []struct {
+		name string
+		svg  string
+	}{
+		{name: "count", svg: string(StatsCount(records).SVG())},
+		{name: "percent", svg: string(StatsPercent(records).SVG())},
+	}
+	for _, test := range tests {
+		counts := elements(t, test.svg)
+		if counts["polyline"] != 2 {
+			t.Errorf("Polyline count mismatch for test: %s, got: %d, want: 2", test.name, counts["polyline"])
+		}
+		if counts["circle"] != 4 {
+			t.Errorf("Point count mismatch for test: %s, got: %d, want: 4", test.name, counts["circle"])
+		}
+	}
+	if !strings.Contains(tests[1].svg, "2016-05-02 Error Per: 2.5</title>") {
+		t.Errorf("Percent chart missing scaled point, got:\n%s", tests[1].svg)
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	records := []*models.StatsRecord{
+		{AuditName: "dns", ErrCount: 10, Datestamp: "2016-05-01"},
+		{AuditName: "dns", ErrCount: 25, Datestamp: "2016-05-10"},
+	}
+	annotations := []*models.Annotation{
+		{Datestamp: "2016-05-03", AuditName: "all", Title: "IPDB <migration>", Link: "https://example.com/?a=1&b=2"},
+		{Datestamp: "2016-05-05", AuditName: "dns", Title: "Audit logic change"},
+		{Datestamp: "2016-06-01", AuditName: "dns", Title: "Out of range"},
+	}
+	svg := string(StatsCount(records).Annotate(annotations).SVG())
+	counts := elements(t, svg)
+	if counts["g"] != 2 {
+		t.Errorf("Marker count mismatch, got: %d, want: 2", counts["g"])
+	}
+	if counts["a"] != 1 {
+		t.Errorf("Marker link count mismatch, got: %d, want: 1", counts["a"])
+	}
+	if !strings.Contains(svg, "<title>2016-05-03 IPDB &lt;migration&gt;</title>") {
+		t.Errorf("Marker title not escaped, got:\n%s", svg)
+	}
+}
+
+func TestBurndown(t *testing.T) {
+	stats := map[string][]*models.StatsRecord{
+		"dns": {
+			{AuditName: "dns", ErrPer: "0.0400", Datestamp: "2016-05-01"},
+			{AuditName: "dns", ErrPer: "0.0300", Datestamp: "2016-05-11"},
+		},
+	}
+	target, _ := models.ParseDatestamp("2016-06-30")
+	now, _ := models.ParseDatestamp("2016-05-12")
+	s := slo.Evaluate([]*slo.Objective{{AuditName: "dns", MaxErrPercent: 1, Target: target}}, stats, now)[0]
+	svg := string(Burndown(s).SVG())
+	counts := elements(t, svg)
+	if counts["polyline"] != 4 {
+		t.Errorf("Polyline count mismatch, got: %d, want: 4", counts["polyline"])
+	}
+	if !strings.Contains(svg, "2016-06-30 Ideal: 1</title>") {
+		t.Errorf("Burn-down missing target point, got:\n%s", svg)
+	}
+}
+
+func TestFixChartsSinglePoint(t *testing.T) {
+	records := []*models.FixStatsRecord{
+		{AuditName: "total", AutofixCount: 8, FixedCount: 6, FixedPer: "0.7500", Datestamp: "2016-05-15"},
+	}
+	for _, svg := range []string{string(FixCount(records).SVG()), string(FixPercent(records).SVG())} {
+		if counts := elements(t, svg); counts["polyline"] == 0 {
+			t.Errorf("Missing polyline, got:\n%s", svg)
+		}
+	}
+}
+
+func TestCompliance(t *testing.T) {
+	tests := []struct {
+		name            string
+		errs, compliant int
+		paths, fullPies int
+	}{
+		{name: "both", errs: 25, compliant: 975, paths: 2},
+		{name: "all_compliant", errs: 0, compliant: 975, fullPies: 1},
+		{name: "empty"},
+	}
+	for _, test := range tests {
+		svg := string(Compliance("Overall <Compliance>", test.errs, test.compliant).SVG())
+		counts := elements(t, svg)
+		if counts["path"] != test.paths || counts["circle"] != test.fullPies {
+			t.Errorf("Slice mismatch for test: %s, got: %d paths %d circles, want: %d paths %d circles",
+				test.name, counts["path"], counts["circle"], test.paths, test.fullPies)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package chart
+
+import (
+	".../go/slo"
+)
+
+// Colors of the burn-down chart series.
+const (
+	targetColor    = "blue"
+	idealColor     = "gray"
+	projectedColor = "orange"
+)
+
+// Burndown returns the burn-down chart of an objective: the error percentage
+// of every snapshot, the objective, the ideal burn-down from the first
+// snapshot to the target date and the projection of the recent trend.
+func Burndown(s *slo.Status) *TimeSeries {
+	actual := &Series{Name: "Error Per", Color: errColor}
+	for _, p := range s.History {
+		actual.Points = append(actual.Points, Point{p.Time, p.ErrPercent})
+	}
+	c := &TimeSeries{Series: []*Series{actual}}
+	if len(s.History) == 0 {
+		return c
+	}
+	first, latest := s.History[0], s.History[len(s.History)-1]
+	end := s.Target
+	if latest.Time.After(end) {
+		end = latest.Time
+	}
+	c.Series = append(c.Series,
+		&Series{Name: "Objective", Color: targetColor, Points: []Point{
+			{first.Time, s.MaxErrPercent},
+			{end, s.MaxErrPercent},
+		}},
+		&Series{Name: "Ideal", Color: idealColor, Points: []Point{
+			{first.Time, first.ErrPercent},
+			{s.Target, s.MaxErrPercent},
+		}},
+	)
+	if !s.Projected.IsZero() && s.Projected.After(latest.Time) {
+		c.Series = append(c.Series, &Series{Name: "Projected", Color: projectedColor, Points: []Point{
+			{latest.Time, latest.ErrPercent},
+			{s.Projected, s.MaxErrPercent},
+		}})
+	}
+	return c
+}
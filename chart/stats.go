@@ -0,0 +1,133 @@
+package chart
+
+import (
+	"strconv"
+
+	".../go/models"
+)
+
+// Colors of the chart series, matching the colors the charts always had.
+const (
+	errColor       = "red"
+	warnColor      = "orange"
+	autofixColor   = "orange"
+	fixedColor     = "green"
+	compliantColor = "blue"
+)
+
+// percent converts a stats ratio such as ErrPer to a percentage.
+func percent(ratio string) float64 {
+	v, err := strconv.ParseFloat(ratio, 64)
+	if err != nil {
+		return 0
+	}
+	return v * 100
+}
+
+// statsSeries returns a series of the value of every stats record.
+func statsSeries(name, color string, records []*models.StatsRecord, value func(*models.StatsRecord) float64) *Series {
+	s := &Series{Name: name, Color: color}
+	for _, r := range records {
+		t, err := models.ParseDatestamp(r.Datestamp)
+		if err != nil {
+			continue
+		}
+		s.Points = append(s.Points, Point{t, value(r)})
+	}
+	return s
+}
+
+// fixSeries returns a series of the value of every autofix stats record.
+func fixSeries(name, color string, records []*models.FixStatsRecord, value func(*models.FixStatsRecord) float64) *Series {
+	s := &Series{Name: name, Color: color}
+	for _, r := range records {
+		t, err := models.ParseDatestamp(r.Datestamp)
+		if err != nil {
+			continue
+		}
+		s.Points = append(s.Points, Point{t, value(r)})
+	}
+	return s
+}
+
+// StatsCount returns the chart of error and warning counts of an audit.
+func StatsCount(records []*models.StatsRecord) *TimeSeries {
+	return &TimeSeries{
+		Series: []*Series{
+			statsSeries("Error", errColor, records, func(r *models.StatsRecord) float64 { return float64(r.ErrCount) }),
+			statsSeries("Warning", warnColor, records, func(r *models.StatsRecord) float64 { return float64(r.WarnCount) }),
+		},
+	}
+}
+
+// StatsPercent returns the chart of error and warning percentages of an
+// audit.
+func StatsPercent(records []*models.StatsRecord) *TimeSeries {
+	return &TimeSeries{
+		Max: 100,
+		Series: []*Series{
+			statsSeries("Error Per", errColor, records, func(r *models.StatsRecord) float64 { return percent(r.ErrPer) }),
+			statsSeries("Warning Per", warnColor, records, func(r *models.StatsRecord) float64 { return percent(r.WarnPer) }),
+		},
+	}
+}
+
+// FixCount returns the chart of autofix attempts and fixes of an audit.
+func FixCount(records []*models.FixStatsRecord) *TimeSeries {
+	return &TimeSeries{
+		Series: []*Series{
+			fixSeries("Autofix", autofixColor, records, func(r *models.FixStatsRecord) float64 { return float64(r.AutofixCount) }),
+			fixSeries("Fixed", fixedColor, records, func(r *models.FixStatsRecord) float64 { return float64(r.FixedCount) }),
+		},
+	}
+}
+
+// FixPercent returns the chart of the autofix success percentage of an
+// audit.
+func FixPercent(records []*models.FixStatsRecord) *TimeSeries {
+	return &TimeSeries{
+		Max: 100,
+		Series: []*Series{
+			fixSeries("Fixed Per", fixedColor, records, func(r *models.FixStatsRecord) float64 { return percent(r.FixedPer) }),
+		},
+	}
+}
+
+// TicketTrend returns the chart of tickets filed per datestamp which are open
+// and closed now.
+func TicketTrend(records []*models.TicketTrendRecord) *TimeSeries {
+	open := &Series{Name: "Open", Color: errColor}
+	closed := &Series{Name: "Closed", Color: fixedColor}
+	for _, r := range records {
+		t, err := models.ParseDatestamp(r.Datestamp)
+		if err != nil {
+			continue
+		}
+		open.Points = append(open.Points, Point{t, float64(r.Open)})
+		closed.Points = append(closed.Points, Point{t, float64(r.Closed)})
+	}
+	return &TimeSeries{Series: []*Series{open, closed}}
+}
+
+// Annotate adds a marker of each annotation to the chart.
+func (c *TimeSeries) Annotate(annotations []*models.Annotation) *TimeSeries {
+	for _, a := range annotations {
+		t, err := models.ParseDatestamp(a.Datestamp)
+		if err != nil {
+			continue
+		}
+		c.Markers = append(c.Markers, &Marker{Time: t, Label: a.Title, Link: a.Link})
+	}
+	return c
+}
+
+// Compliance returns the pie chart of error and compliant counts.
+func Compliance(title string, errCount, compliant int) *Pie {
+	return &Pie{
+		Title: title,
+		Slices: []*Slice{
+			{Label: "error", Value: float64(errCount), Color: errColor},
+			{Label: "compliant", Value: float64(compliant), Color: compliantColor},
+		},
+	}
+}
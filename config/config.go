@@ -0,0 +1,147 @@
+// Package config loads the Dragonwell site configuration.
+package config
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+)
+
+// configFile is the json file where the site configuration is stored.
+const configFile = ".../go/config/dragonwell.json"
+
+// Config contains the site configuration.
+type Config struct {
+	Alerts     AlertConfig        `json:"alerts"`
+	Digest     DigestConfig       `json:"digest"`
+	Health     HealthConfig       `json:"health"`
+	Tickets    TicketsConfig      `json:"tickets"`
+	Objectives []*ObjectiveConfig `json:"objectives"`
+	Ingest     IngestConfig       `json:"ingest"`
+	// Links are the URL templates of external links keyed on field, such
+	// as "netblock" and "ticket". {value} is replaced by the field value.
+	Links map[string]string `json:"links"`
+}
+
+// AlertConfig contains the alerting rules and where alerts are sent to.
+type AlertConfig struct {
+	Rules    []*AlertRule     `json:"rules"`
+	Webhooks []*WebhookConfig `json:"webhooks"`
+	Email    *EmailConfig     `json:"email"`
+}
+
+// AlertRule is evaluated on the audit stats of every new snapshot.
+type AlertRule struct {
+	// AuditName is the audit the rule applies to, empty or "*" for all.
+	AuditName string `json:"audit_name"`
+	// Type is one of "err_count_jump", "err_count_jump_percent" and
+	// "err_per_above".
+	Type      string  `json:"type"`
+	Threshold float64 `json:"threshold"`
+}
+
+// WebhookConfig is an URL alerts are posted to as json.
+type WebhookConfig struct {
+	URL string `json:"url"`
+}
+
+// EmailConfig contains the SMTP relay and addresses of notification mails.
+type EmailConfig struct {
+	SMTPAddr string   `json:"smtp_addr"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+}
+
+// DigestConfig contains the recipients of the compliance digest. Mails are
+// sent through the App Engine mail API unless an SMTP relay is given.
+type DigestConfig struct {
+	SMTPAddr string   `json:"smtp_addr"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+}
+
+// HealthConfig contains the readiness thresholds.
+type HealthConfig struct {
+	// MaxSnapshotAgeHours is the age of the newest snapshot after which the
+	// site reports not ready.
+	MaxSnapshotAgeHours int `json:"max_snapshot_age_hours"`
+}
+
+// TicketsConfig contains the ticket SLAs and the recipients of the ticket
+// reconciliation report.
+type TicketsConfig struct {
+	// DefaultSLADays is the number of days an open ticket may age before it
+	// breaches its SLA, unless SLADays has an entry for its audit.
+	DefaultSLADays int            `json:"default_sla_days"`
+	SLADays        map[string]int `json:"sla_days"`
+	// ReconcileTo receives the reconciliation report, sent with the digest
+	// mail settings.
+	ReconcileTo []string `json:"reconcile_to"`
+}
+
+// SLA returns the SLA days of tickets of the audit, 0 if there is none.
+func (c *TicketsConfig) SLA(auditName string) int {
+	if days, ok := c.SLADays[auditName]; ok {
+		return days
+	}
+	return c.DefaultSLADays
+}
+
+// ObjectiveConfig is a compliance objective of an audit: the error
+// percentage is to be at or below MaxErrPercent by TargetDate.
+type ObjectiveConfig struct {
+	AuditName     string  `json:"audit_name"`
+	MaxErrPercent float64 `json:"max_err_percent"`
+	// TargetDate is a datestamp such as "2017-01-01".
+	TargetDate string `json:"target_date"`
+}
+
+// IngestConfig contains the audit producers allowed to deliver results.
+type IngestConfig struct {
+	Producers []*ProducerConfig `json:"producers"`
+}
+
+// ProducerConfig is an audit producer. Producers authenticate with a bearer
+// token, only the SHA-256 hash of which is configured.
+type ProducerConfig struct {
+	Name string `json:"name"`
+	// TokenSHA256 is the hex encoded SHA-256 hash of the token.
+	TokenSHA256 string `json:"token_sha256"`
+	// AuditNames are the audits the producer may deliver results of.
+	AuditNames []string `json:"audit_names"`
+}
+
+// current is the configuration loaded from configFile.
+var current *Config
+
+// Load reads the configuration from the json file at path. A missing file
+// results in an empty configuration.
+func Load(path string) (*Config, error) {
+	cfg := &Config{}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Get returns the site configuration.
+func Get() *Config {
+	return current
+}
+
+// init loads the configuration from configFile. This function will panic if
+// the configuration cannot be parsed.
+func init() {
+	cfg, err := Load(configFile)
+	if err != nil {
+		panic(err)
+	}
+	current = cfg
+}
@@ -0,0 +1,230 @@
+// Package digest builds the compliance digest report sent to managers.
+package digest
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"sort"
+	"strconv"
+	"text/template"
+	"time"
+
+	".../go/mail"
+	".../go/models"
+)
+
+// Source provides the data a digest is built from.
+type Source interface {
+	AuditStats() (map[string][]*models.StatsRecord, *models.StatsRecord, error)
+	FixStats() (map[string][]*models.FixStatsRecord, error)
+	AuditTickets() ([]*models.TicketRecord, error)
+}
+
+// Options tune the content of a digest.
+type Options struct {
+	// Period is the time span movers are computed over.
+	Period time.Duration
+	// TopMovers is the number of audits listed as top movers.
+	TopMovers int
+	// TicketAge is the age after which open tickets are listed as aging.
+	TicketAge time.Duration
+}
+
+// DefaultOptions are the options of the weekly digest.
+var DefaultOptions = Options{
+	Period:    7 * 24 * time.Hour,
+	TopMovers: 10,
+	TicketAge: 30 * 24 * time.Hour,
+}
+
+// Digest is a compliance summary over a period.
+type Digest struct {
+	Generated time.Time
+	Since     string
+	Snapshot  string
+	Overall   Overall
+	Movers    []*Mover
+	Autofix   []*models.FixStatsRecord
+	Tickets   []*AgingTicket
+}
+
+// Overall is the compliance of the corp_reports row.
+type Overall struct {
+	ErrCount     int
+	TotalCount   int
+	CompliantPer string
+}
+
+// Mover is the change of the error count of an audit over the period.
+type Mover struct {
+	AuditName string
+	Previous  int
+	Current   int
+	Delta     int
+}
+
+// AgingTicket is an open ticket older than Options.TicketAge.
+type AgingTicket struct {
+	*models.TicketRecord
+	AgeDays int
+}
+
+// Build creates the digest of the data in src as of now.
+func Build(src Source, now time.Time, opts Options) (*Digest, error) {
+	stats, overallStats, err := src.AuditStats()
+	if err != nil {
+		return nil, err
+	}
+	fixStats, err := src.FixStats()
+	if err != nil {
+		return nil, err
+	}
+	tickets, err := src.AuditTickets()
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Digest{Generated: now}
+	if overallStats != nil {
+		d.Overall = Overall{ErrCount: overallStats.ErrCount, TotalCount: overallStats.TotalCount}
+		if overallStats.TotalCount > 0 {
+			compliant := overallStats.TotalCount - overallStats.ErrCount
+			d.Overall.CompliantPer = strconv.FormatFloat(float64(compliant)*100/float64(overallStats.TotalCount), 'f', 2, 64)
+		}
+	}
+
+	for auditName, records := range stats {
+		m, snapshot, since := mover(records, opts.Period)
+		if m == nil {
+			continue
+		}
+		m.AuditName = auditName
+		d.Movers = append(d.Movers, m)
+		if snapshot > d.Snapshot {
+			d.Snapshot = snapshot
+		}
+		if d.Since == "" || since < d.Since {
+			d.Since = since
+		}
+	}
+	sort.Slice(d.Movers, func(i, j int) bool {
+		di, dj := abs(d.Movers[i].Delta), abs(d.Movers[j].Delta)
+		if di != dj {
+			return di > dj
+		}
+		return d.Movers[i].AuditName < d.Movers[j].AuditName
+	})
+	if opts.TopMovers > 0 && len(d.Movers) > opts.TopMovers {
+		d.Movers = d.Movers[:opts.TopMovers]
+	}
+
+	for _, records := range fixStats {
+		var latest *models.FixStatsRecord
+		for _, r := range records {
+			if latest == nil || r.Datestamp > latest.Datestamp {
+				latest = r
+			}
+		}
+		if latest != nil {
+			d.Autofix = append(d.Autofix, latest)
+		}
+	}
+	sort.Slice(d.Autofix, func(i, j int) bool { return d.Autofix[i].AuditName < d.Autofix[j].AuditName })
+
+	for _, t := range tickets {
+		if t.IsClosed() {
+			continue
+		}
+		age, err := t.Age(now)
+		if err != nil {
+			continue
+		}
+		if age >= opts.TicketAge {
+			d.Tickets = append(d.Tickets, &AgingTicket{t, int(age.Hours() / 24)})
+		}
+	}
+	sort.Slice(d.Tickets, func(i, j int) bool {
+		if d.Tickets[i].AgeDays != d.Tickets[j].AgeDays {
+			return d.Tickets[i].AgeDays > d.Tickets[j].AgeDays
+		}
+		return d.Tickets[i].TicketID < d.Tickets[j].TicketID
+	})
+	return d, nil
+}
+
+// mover compares the newest record with the newest one at least period
+// older, or the oldest record if there is none. It returns the snapshots
+// compared.
+func mover(records []*models.StatsRecord, period time.Duration) (*Mover, string, string) {
+	if len(records) == 0 {
+		return nil, "", ""
+	}
+	sorted := make([]*models.StatsRecord, len(records))
+	copy(sorted, records)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Datestamp < sorted[j].Datestamp })
+	latest := sorted[len(sorted)-1]
+	previous := sorted[0]
+	if t, err := models.ParseDatestamp(latest.Datestamp); err == nil {
+		cutoff := t.Add(-period).Format(models.DateFormat)
+		for _, r := range sorted {
+			if r.Datestamp <= cutoff {
+				previous = r
+			}
+		}
+	}
+	return &Mover{
+		Previous: previous.ErrCount,
+		Current:  latest.ErrCount,
+		Delta:    latest.ErrCount - previous.ErrCount,
+	}, latest.Datestamp, previous.Datestamp
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
+
+var (
+	textTemplate = template.Must(template.New("digest").Parse(textDigest))
+	htmlTemplate = htmltemplate.Must(htmltemplate.New("digest").Parse(htmlDigest))
+)
+
+// Text returns the plain text rendering of the digest.
+func (d *Digest) Text() (string, error) {
+	b := bytes.NewBuffer([]byte{})
+	if err := textTemplate.Execute(b, d); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// HTML returns the HTML rendering of the digest, usable as a page fragment
+// and as a mail body.
+func (d *Digest) HTML() (htmltemplate.HTML, error) {
+	b := bytes.NewBuffer([]byte{})
+	if err := htmlTemplate.Execute(b, d); err != nil {
+		return "", err
+	}
+	return htmltemplate.HTML(b.String()), nil
+}
+
+// Message returns the digest as a mail message.
+func (d *Digest) Message(from string, to []string) (*mail.Message, error) {
+	text, err := d.Text()
+	if err != nil {
+		return nil, err
+	}
+	html, err := d.HTML()
+	if err != nil {
+		return nil, err
+	}
+	return &mail.Message{
+		From:    from,
+		To:      to,
+		Subject: "[Dragonwell] Compliance digest " + d.Snapshot,
+		Text:    text,
+		HTML:    string(html),
+	}, nil
+}
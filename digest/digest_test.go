@@ -0,0 +1,90 @@
+package digest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	".../go/models"
+)
+
+// fakeSource serves fixed records as a digest Source.
+type fakeSource struct{}
+
+func (fakeSource) AuditStats() (map[string][]*models.StatsRecord, *models.StatsRecord, error) {
+	return map[string][]*models.StatsRecord{
+		"dns": {
+			{AuditName: "dns", ErrCount: 40, Datestamp: "2016-05-15"},
+			{AuditName: "dns", ErrCount: 12, Datestamp: "2016-05-08"},
+			{AuditName: "dns", ErrCount: 10, Datestamp: "2016-05-01"},
+		},
+		"vlan": {
+			{AuditName: "vlan", ErrCount: 5, Datestamp: "2016-05-15"},
+			{AuditName: "vlan", ErrCount: 20, Datestamp: "2016-05-08"},
+		},
+		"gateway": {
+			{AuditName: "gateway", ErrCount: 3, Datestamp: "2016-05-15"},
+			{AuditName: "gateway", ErrCount: 3, Datestamp: "2016-05-08"},
+		},
+	}, &models.StatsRecord{
+		ErrCount:   25,
+		TotalCount: 1000,
+	}, nil
+}
+
+func (fakeSource) FixStats() (map[string][]*models.FixStatsRecord, error) {
+	return map[string][]*models.FixStatsRecord{
+		"total": {
+			{AuditName: "total", AutofixCount: 10, FixedCount: 5, FixedPer: "0.5000", Datestamp: "2016-05-08"},
+			{AuditName: "total", AutofixCount: 8, FixedCount: 6, FixedPer: "0.7500", Datestamp: "2016-05-15"},
+		},
+	}, nil
+}
+
+func (fakeSource) AuditTickets() ([]*models.TicketRecord, error) {
+	return []*models.TicketRecord{
+		{Summary: "old open", State: "Assigned", Datestamp: "2016-03-01", TicketID: 1},
+		{Summary: "old closed", State: "Fixed", Datestamp: "2016-03-01", TicketID: 2},
+		{Summary: "new open", State: "New", Datestamp: "2016-05-10", TicketID: 3},
+	}, nil
+}
+
+func TestBuild(t *testing.T) {
+	now := time.Date(2016, 5, 16, 8, 0, 0, 0, time.UTC)
+	opts := DefaultOptions
+	opts.TopMovers = 2
+	d, err := Build(fakeSource{}, now, opts)
+	if err != nil {
+		t.Fatalf("Build error: %v", err)
+	}
+
+	if d.Overall.CompliantPer != "97.50" {
+		t.Errorf("CompliantPer mismatch, got: %s, want: 97.50", d.Overall.CompliantPer)
+	}
+	if d.Snapshot != "2016-05-15" || d.Since != "2016-05-08" {
+		t.Errorf("Period mismatch, got: %s - %s, want: 2016-05-08 - 2016-05-15", d.Since, d.Snapshot)
+	}
+	if len(d.Movers) != 2 || d.Movers[0].AuditName != "dns" || d.Movers[0].Delta != 28 ||
+		d.Movers[1].AuditName != "vlan" || d.Movers[1].Delta != -15 {
+		t.Errorf("Movers mismatch, got: %+v %+v", d.Movers[0], d.Movers[1])
+	}
+	if len(d.Autofix) != 1 || d.Autofix[0].FixedPer != "0.7500" {
+		t.Errorf("Autofix mismatch, got: %+v", d.Autofix)
+	}
+	if len(d.Tickets) != 1 || d.Tickets[0].TicketID != 1 || d.Tickets[0].AgeDays != 76 {
+		t.Errorf("Aging tickets mismatch, got: %+v", d.Tickets)
+	}
+
+	text, err := d.Text()
+	if err != nil {
+		t.Fatalf("Text error: %v", err)
+	}
+	for _, want := range []string{"Overall compliance: 97.50%", "dns", "+28", "old open"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text digest missing %q, got:\n%s", want, text)
+		}
+	}
+	if _, err := d.HTML(); err != nil {
+		t.Errorf("HTML error: %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package digest
+
+// textDigest is the template of the plain text digest.
+const textDigest = `Dragonwell compliance digest {{.Since}} - {{.Snapshot}}
+
+Overall compliance: {{if .Overall.CompliantPer}}{{.Overall.CompliantPer}}%{{else}}n/a{{end}} ({{.Overall.ErrCount}} errors of {{.Overall.TotalCount}})
+
+Top movers:
+{{range .Movers}}  {{printf "%-30s %6d -> %6d (%+d)" .AuditName .Previous .Current .Delta}}
+{{else}}  none
+{{end}}
+Autofix success rate:
+{{range .Autofix}}  {{printf "%-30s %6d of %6d fixed (%s)" .AuditName .FixedCount .AutofixCount .FixedPer}}
+{{else}}  none
+{{end}}
+Aging tickets:
+{{range .Tickets}}  {{printf "%-10d %4d days  %-12s %s" .TicketID .AgeDays .State .Summary}}
+{{else}}  none
+{{end}}`
+
+// htmlDigest is the template of the HTML digest. It only uses inline styles
+// so it renders the same in mail clients and on the site.
+const htmlDigest = `<div id="id_digest">
+<h2 style="font-family: Arial;">Compliance digest {{.Since}} - {{.Snapshot}}</h2>
+<p><b>Overall compliance:</b>
+{{if .Overall.CompliantPer}}{{.Overall.CompliantPer}}%{{else}}n/a{{end}}
+({{.Overall.ErrCount}} errors of {{.Overall.TotalCount}})</p>
+
+<h3 style="font-family: Arial;">Top movers</h3>
+<table border=1 cellspacing="0" style="border-width:thin">
+  <tr bgcolor=#99ccff><th>Audit Name</th><th>Previous</th><th>Current</th><th>Change</th></tr>
+  {{range .Movers}}
+  <tr>
+    <td><a href="/auditreport/?auditname={{.AuditName}}">{{.AuditName}}</a></td>
+    <td>{{.Previous}}</td>
+    <td>{{.Current}}</td>
+    <td style="color: {{if gt .Delta 0}}red{{else}}green{{end}};">{{printf "%+d" .Delta}}</td>
+  </tr>
+  {{end}}
+</table>
+
+<h3 style="font-family: Arial;">Autofix success rate</h3>
+<table border=1 cellspacing="0" style="border-width:thin">
+  <tr bgcolor=#99ccff><th>Audit Name</th><th>Autofix</th><th>Fixed</th><th>Fixed Per</th><th>Date</th></tr>
+  {{range .Autofix}}
+  <tr><td>{{.AuditName}}</td><td>{{.AutofixCount}}</td><td>{{.FixedCount}}</td><td>{{.FixedPer}}</td><td>{{.Datestamp}}</td></tr>
+  {{end}}
+</table>
+
+<h3 style="font-family: Arial;">Aging tickets</h3>
+<table border=1 cellspacing="0" style="border-width:thin">
+  <tr bgcolor=#99ccff><th>Ticket</th><th>Age (days)</th><th>State</th><th>Audit Name</th><th>Audit Code</th><th>Summary</th></tr>
+  {{range .Tickets}}
+  <tr><td>{{.TicketID}}</td><td>{{.AgeDays}}</td><td>{{.State}}</td><td>{{.AuditName}}</td><td>{{.AuditCode}}</td><td>{{.Summary}}</td></tr>
+  {{end}}
+</table>
+</div>
+`
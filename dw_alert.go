@@ -0,0 +1,35 @@
+package models
+
+import (
+	"fmt"
+)
+
+// FiringAlerts fetches the keys of alerts which have been notified and did
+// not clear yet.
+func (s *sqlStore) FiringAlerts() (map[string]bool, error) {
+	r, err := s.db.Query(fmt.Sprintf(alertStateSelect, alertStateTable))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var key string
+	firing := make(map[string]bool)
+	for r.Next() {
+		if err := r.Scan(&key); err != nil {
+			return nil, err
+		}
+		firing[key] = true
+	}
+	return firing, r.Err()
+}
+
+// SetAlertFiring records the alert key as notified, or removes it once the
+// alert cleared.
+func (s *sqlStore) SetAlertFiring(key string, firing bool) error {
+	query := alertStateDelete
+	if firing {
+		query = alertStateInsert
+	}
+	_, err := s.db.Exec(fmt.Sprintf(query, alertStateTable), key)
+	return err
+}
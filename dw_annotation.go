@@ -0,0 +1,138 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// AnnotationAllAudits is the audit name of annotations shown on the charts of
+// every audit.
+const AnnotationAllAudits = "all"
+
+// ErrNoAnnotation is returned when updating or deleting an annotation which
+// does not exist.
+var ErrNoAnnotation = errors.New("annotation not found")
+
+// Annotation marks an operational event, such as an IPDB migration or an
+// audit logic change, on the chart timeline.
+type Annotation struct {
+	ID        int    `json:"id"`
+	Datestamp string `json:"date"`
+	AuditName string `json:"audit_name"`
+	Title     string `json:"title"`
+	Link      string `json:"link"`
+}
+
+// Validate checks the fields of the annotation.
+func (a *Annotation) Validate() error {
+	if _, err := ParseDatestamp(a.Datestamp); err != nil {
+		return fmt.Errorf("invalid date %q", a.Datestamp)
+	}
+	if a.AuditName == "" {
+		return errors.New("missing audit name")
+	}
+	if a.Title == "" {
+		return errors.New("missing title")
+	}
+	if a.Link != "" {
+		u, err := url.Parse(a.Link)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("invalid link %q", a.Link)
+		}
+	}
+	return nil
+}
+
+// AppliesTo reports whether the annotation is shown on the charts of the
+// audit.
+func (a *Annotation) AppliesTo(auditName string) bool {
+	return a.AuditName == AnnotationAllAudits || a.AuditName == auditName
+}
+
+// AnnotationsByAudit returns the annotations applying to each of the audits
+// keyed on audit name.
+func AnnotationsByAudit(annotations []*Annotation, auditNames []string) map[string][]*Annotation {
+	m := make(map[string][]*Annotation)
+	for _, auditName := range auditNames {
+		for _, a := range annotations {
+			if a.AppliesTo(auditName) {
+				m[auditName] = append(m[auditName], a)
+			}
+		}
+	}
+	return m
+}
+
+// Annotations fetches all annotations ordered by date.
+func (s *sqlStore) Annotations() ([]*Annotation, error) {
+	r, err := s.db.Query(fmt.Sprintf(annotationSelect, annotationTable))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var rs []*Annotation
+	for r.Next() {
+		a := &Annotation{}
+		if err := r.Scan(&a.ID, &a.Datestamp, &a.AuditName, &a.Title, &a.Link); err != nil {
+			return nil, err
+		}
+		rs = append(rs, a)
+	}
+	return rs, r.Err()
+}
+
+// SaveAnnotation adds the annotation and sets its ID if the ID is 0, and
+// updates the annotation with the ID otherwise. It returns ErrNoAnnotation if
+// there is no annotation with the ID.
+func (s *sqlStore) SaveAnnotation(a *Annotation) error {
+	if a.ID == 0 {
+		res, err := s.db.Exec(fmt.Sprintf(annotationInsert, annotationTable), a.Datestamp, a.AuditName, a.Title, a.Link)
+		if err != nil {
+			return err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		a.ID = int(id)
+		return nil
+	}
+	res, err := s.db.Exec(fmt.Sprintf(annotationUpdate, annotationTable), a.Datestamp, a.AuditName, a.Title, a.Link, a.ID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+	// MySQL counts changed rows, so an update to the same values affects
+	// none.
+	var count int
+	if err := s.db.QueryRow(fmt.Sprintf(annotationCountSelect, annotationTable), a.ID).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrNoAnnotation
+	}
+	return nil
+}
+
+// DeleteAnnotation deletes the annotation with the ID.
+func (s *sqlStore) DeleteAnnotation(id int) error {
+	res, err := s.db.Exec(fmt.Sprintf(annotationDelete, annotationTable), id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoAnnotation
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// findingKeySep separates the fields of a finding key.
+const findingKeySep = "|"
+
+// MaxCommentLength bounds the length of a comment body in bytes.
+const MaxCommentLength = 4000
+
+// commentTimeFormat is the layout of the created column.
+const commentTimeFormat = "2006-01-02 15:04:05"
+
+// ErrNoComment is returned when deleting a comment which does not exist or
+// was written by another author.
+var ErrNoComment = errors.New("comment not found")
+
+// FindingKey returns the stable identity of a finding across snapshots,
+// "netblock|audit_name|audit_code".
+func FindingKey(netblock, auditName, auditCode string) string {
+	return strings.Join([]string{netblock, auditName, auditCode}, findingKeySep)
+}
+
+// FindingKey returns the stable identity of the finding of the record.
+func (ar *AuditRecord) FindingKey() string {
+	return FindingKey(ar.Netblock, ar.AuditName, ar.AuditCode)
+}
+
+// SplitFindingKey returns the netblock, audit name and audit code of a
+// finding key.
+func SplitFindingKey(key string) (string, string, string, error) {
+	fields := strings.Split(key, findingKeySep)
+	if len(fields) != 3 || fields[0] == "" || fields[1] == "" || fields[2] == "" {
+		return "", "", "", fmt.Errorf("invalid finding %q", key)
+	}
+	return fields[0], fields[1], fields[2], nil
+}
+
+// Comment is a message of the discussion thread of a finding.
+type Comment struct {
+	ID int `json:"id"`
+	// Finding is the finding key of the thread.
+	Finding string `json:"finding"`
+	Author  string `json:"author"`
+	Body    string `json:"body"`
+	Created string `json:"created"`
+}
+
+// CommentStore stores the discussion threads of findings.
+type CommentStore interface {
+	// Comments fetches the thread of the finding, oldest first.
+	Comments(finding string) ([]*Comment, error)
+	// CommentCounts fetches the number of comments of the findings of the
+	// audit keyed on finding key.
+	CommentCounts(auditName string) (map[string]int, error)
+	// AddComment adds the comment and sets its ID and creation time.
+	AddComment(c *Comment) error
+	// DeleteComment deletes the comment with the ID if it was written by
+	// author.
+	DeleteComment(id int, author string) error
+	// CommentsRevision returns a value which changes with every added or
+	// deleted comment.
+	CommentsRevision() (string, error)
+}
+
+// Validate checks the fields of the comment.
+func (c *Comment) Validate() error {
+	if _, _, _, err := SplitFindingKey(c.Finding); err != nil {
+		return err
+	}
+	if strings.TrimSpace(c.Body) == "" {
+		return errors.New("empty comment")
+	}
+	if len(c.Body) > MaxCommentLength {
+		return fmt.Errorf("comment exceeds %d bytes", MaxCommentLength)
+	}
+	return nil
+}
+
+// Comments fetches the thread of the finding, oldest first.
+func (s *sqlStore) Comments(finding string) ([]*Comment, error) {
+	netblock, auditName, auditCode, err := SplitFindingKey(finding)
+	if err != nil {
+		return nil, err
+	}
+	r, err := s.db.Query(fmt.Sprintf(commentSelect, commentTable), netblock, auditName, auditCode)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var rs []*Comment
+	for r.Next() {
+		c := &Comment{}
+		if err := r.Scan(&c.ID, &netblock, &auditName, &auditCode, &c.Author, &c.Body, &c.Created); err != nil {
+			return nil, err
+		}
+		c.Finding = FindingKey(netblock, auditName, auditCode)
+		rs = append(rs, c)
+	}
+	return rs, r.Err()
+}
+
+// CommentCounts fetches the number of comments of the findings of the audit.
+func (s *sqlStore) CommentCounts(auditName string) (map[string]int, error) {
+	r, err := s.db.Query(fmt.Sprintf(commentCountSelect, commentTable), auditName)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var netblock, auditCode string
+	var count int
+	counts := make(map[string]int)
+	for r.Next() {
+		if err := r.Scan(&netblock, &auditCode, &count); err != nil {
+			return nil, err
+		}
+		counts[FindingKey(netblock, auditName, auditCode)] = count
+	}
+	return counts, r.Err()
+}
+
+// AddComment adds the comment and sets its ID and creation time.
+func (s *sqlStore) AddComment(c *Comment) error {
+	netblock, auditName, auditCode, err := SplitFindingKey(c.Finding)
+	if err != nil {
+		return err
+	}
+	created := time.Now().UTC().Format(commentTimeFormat)
+	res, err := s.db.Exec(fmt.Sprintf(commentInsert, commentTable), netblock, auditName, auditCode, c.Author, c.Body, created)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	c.ID = int(id)
+	c.Created = created
+	return nil
+}
+
+// DeleteComment deletes the comment with the ID if it was written by author.
+func (s *sqlStore) DeleteComment(id int, author string) error {
+	res, err := s.db.Exec(fmt.Sprintf(commentDelete, commentTable), id, author)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoComment
+	}
+	return nil
+}
+
+// CommentsRevision returns the number of comments and the newest ID.
+func (s *sqlStore) CommentsRevision() (string, error) {
+	var count, maxID int
+	if err := s.db.QueryRow(fmt.Sprintf(commentRevisionSelect, commentTable)).Scan(&count, &maxID); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d:%d", count, maxID), nil
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFindingKey(t *testing.T) {
+	ar := &AuditRecord{Netblock: "10.1.2.0/24", AuditName: "vlan", AuditCode: "E1"}
+	if got, want := ar.FindingKey(), "10.1.2.0/24|vlan|E1"; got != want {
+		t.Errorf("FindingKey mismatch, got: %q, want: %q", got, want)
+	}
+	netblock, auditName, auditCode, err := SplitFindingKey(ar.FindingKey())
+	if err != nil {
+		t.Fatalf("SplitFindingKey error: %v", err)
+	}
+	if netblock != ar.Netblock || auditName != ar.AuditName || auditCode != ar.AuditCode {
+		t.Errorf("SplitFindingKey mismatch, got: %s %s %s, want: %s %s %s",
+			netblock, auditName, auditCode, ar.Netblock, ar.AuditName, ar.AuditCode)
+	}
+}
+
+func TestSplitFindingKey(t *testing.T) {
+	for _, key := range []string{"", "10.1.2.0/24", "10.1.2.0/24|vlan", "10.1.2.0/24|vlan|E1|x",
+		"|vlan|E1", "10.1.2.0/24||E1", "10.1.2.0/24|vlan|"} {
+		if _, _, _, err := SplitFindingKey(key); err == nil {
+			t.Errorf("SplitFindingKey(%q) succeeded, want error", key)
+		}
+	}
+}
+
+func TestCommentValidate(t *testing.T) {
+	tests := []struct {
+		finding, body string
+		valid         bool
+	}{
+		{"10.1.2.0/24|vlan|E1", "looking into it", true},
+		{"10.1.2.0/24|vlan|E1", strings.Repeat("x", MaxCommentLength), true},
+		{"10.1.2.0/24|vlan|E1", strings.Repeat("x", MaxCommentLength+1), false},
+		{"10.1.2.0/24|vlan|E1", " \n\t", false},
+		{"10.1.2.0/24|vlan", "looking into it", false},
+	}
+	for _, test := range tests {
+		c := &Comment{Finding: test.finding, Body: test.body}
+		if err := c.Validate(); (err == nil) != test.valid {
+			t.Errorf("Validate mismatch for %q (%d bytes), got: %v, want valid: %v",
+				test.finding, len(test.body), err, test.valid)
+		}
+	}
+}
@@ -4,6 +4,7 @@ package models
 import (
 	"database/sql"
 	"fmt"
+	"net"
 	"strings"
 	"time"
 
@@ -29,15 +30,30 @@ const (
 	auditTable      = "ipdb_audit"
 	auditStatsTable = "ipdb_audit_stats"
 	ticketTable     = "ipdb_ticket"
+	alertStateTable = "ipdb_alert_state"
+	annotationTable = "ipdb_annotation"
+	batchTable      = "ipdb_ingest_batch"
+	auditStageTable = "ipdb_audit_staging"
+	statsStageTable = "ipdb_audit_stats_staging"
+	commentTable    = "ipdb_comment"
 	auditSelect     = `
 SELECT netblock, tags, vlan_id, building, gateway, attributes,
 child_attributes, expected_value, network, audit_name,
 audit_code, correlates, audit_msg, severity, state, fix_state, fix_msg, tickets, datestamp, id FROM %s
 where datestamp=? and audit_name=? limit 10000`
+	snapshotSelect = `
+SELECT netblock, tags, vlan_id, building, gateway, attributes,
+child_attributes, expected_value, network, audit_name,
+audit_code, correlates, audit_msg, severity, state, fix_state, fix_msg, tickets, datestamp, id FROM %s
+where datestamp=?`
 	auditCountSelect = `
 SELECT audit_name, COUNT(*) FROM %s WHERE datestamp=? GROUP BY audit_name ORDER BY audit_name`
 	snapshotsSelect = `
 SELECT datestamp FROM %s GROUP BY datestamp ORDER BY datestamp DESC`
+	latestSnapshotSelect = `
+SELECT MAX(datestamp) FROM %s`
+	ticketTrendSelect = `
+SELECT datestamp, state, COUNT(*) FROM %s GROUP BY datestamp, state ORDER BY datestamp`
 	statsSelect = `
 SELECT audit_name, err_count, warn_count, err_per, warn_per, datestamp FROM %s WHERE audit_name <> 'corp_reports'`
 	fixStatsSelect = `
@@ -46,6 +62,70 @@ SELECT audit_name, autofix_count, fixed_count, datestamp FROM %s WHERE autofix_c
 SELECT err_count, total, err_per FROM %s WHERE audit_name='corp_reports' order by datestamp desc limit 1`
 	ticketsSelect = `
 SELECT summary, description, audit_name, audit_code, state, datestamp, ticket_id FROM %s`
+	netblockSelect = `
+SELECT netblock, tags, vlan_id, building, gateway, attributes,
+child_attributes, expected_value, network, audit_name,
+audit_code, correlates, audit_msg, severity, state, fix_state, fix_msg, tickets, datestamp, id FROM %s
+where netblock LIKE ? ORDER BY datestamp DESC`
+	alertStateSelect = `
+SELECT alert_key FROM %s`
+	alertStateInsert = `
+INSERT IGNORE INTO %s (alert_key) VALUES (?)`
+	alertStateDelete = `
+DELETE FROM %s WHERE alert_key=?`
+	annotationSelect = `
+SELECT id, datestamp, audit_name, title, link FROM %s ORDER BY datestamp`
+	annotationInsert = `
+INSERT INTO %s (datestamp, audit_name, title, link) VALUES (?, ?, ?, ?)`
+	annotationUpdate = `
+UPDATE %s SET datestamp=?, audit_name=?, title=?, link=? WHERE id=?`
+	annotationDelete = `
+DELETE FROM %s WHERE id=?`
+	annotationCountSelect = `
+SELECT COUNT(*) FROM %s WHERE id=?`
+	batchSelect = `
+SELECT producer, datestamp FROM %s WHERE batch_id=?`
+	batchInsert = `
+INSERT IGNORE INTO %s (batch_id, producer, datestamp, complete) VALUES (?, ?, ?, ?)`
+	auditStageInsert = `
+INSERT INTO %s (producer, netblock, tags, vlan_id, building, gateway, attributes,
+child_attributes, expected_value, network, audit_name,
+audit_code, correlates, audit_msg, severity, state, fix_state, fix_msg, tickets, datestamp)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	statsStageInsert = `
+INSERT INTO %s (producer, audit_name, err_count, warn_count, err_per, warn_per, total,
+autofix_count, fixed_count, datestamp) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	stagedDelete = `
+DELETE FROM %s WHERE datestamp=? AND audit_name IN
+(SELECT audit_name FROM %s WHERE datestamp=? AND producer=?
+UNION SELECT audit_name FROM %s WHERE datestamp=? AND producer=?)`
+	auditStagedCopy = `
+INSERT INTO %s (netblock, tags, vlan_id, building, gateway, attributes,
+child_attributes, expected_value, network, audit_name,
+audit_code, correlates, audit_msg, severity, state, fix_state, fix_msg, tickets, datestamp)
+SELECT netblock, tags, vlan_id, building, gateway, attributes,
+child_attributes, expected_value, network, audit_name,
+audit_code, correlates, audit_msg, severity, state, fix_state, fix_msg, tickets, datestamp
+FROM %s WHERE datestamp=? AND producer=?`
+	statsStagedCopy = `
+INSERT INTO %s (audit_name, err_count, warn_count, err_per, warn_per, total,
+autofix_count, fixed_count, datestamp)
+SELECT audit_name, err_count, warn_count, err_per, warn_per, total,
+autofix_count, fixed_count, datestamp
+FROM %s WHERE datestamp=? AND producer=?`
+	stageDelete = `
+DELETE FROM %s WHERE datestamp=? AND producer=?`
+	commentSelect = `
+SELECT id, netblock, audit_name, audit_code, author, body, created FROM %s
+WHERE netblock=? AND audit_name=? AND audit_code=? ORDER BY created, id`
+	commentCountSelect = `
+SELECT netblock, audit_code, COUNT(*) FROM %s WHERE audit_name=? GROUP BY netblock, audit_code`
+	commentInsert = `
+INSERT INTO %s (netblock, audit_name, audit_code, author, body, created) VALUES (?, ?, ?, ?, ?, ?)`
+	commentDelete = `
+DELETE FROM %s WHERE id=? AND author=?`
+	commentRevisionSelect = `
+SELECT COUNT(*), COALESCE(MAX(id), 0) FROM %s`
 )
 
 // AuditRecord contains the audit result data for template execution.
@@ -95,6 +175,19 @@ type FixStatsRecord struct {
 	Datestamp    string
 }
 
+// DateFormat is the layout of datestamps.
+const DateFormat = "2006-01-02"
+
+// closedStates are the ticket states in which tickets are no longer worked on.
+var closedStates = map[string]bool{
+	"closed":    true,
+	"fixed":     true,
+	"verified":  true,
+	"obsolete":  true,
+	"duplicate": true,
+	"wontfix":   true,
+}
+
 // TicketRecord contains the ticket related data for template execution.
 type TicketRecord struct {
 	Summary     string
@@ -106,6 +199,25 @@ type TicketRecord struct {
 	TicketID    int
 }
 
+// IsClosed reports whether the ticket is in a closed state.
+func (t *TicketRecord) IsClosed() bool {
+	return closedStates[strings.ToLower(t.State)]
+}
+
+// Age returns how long ago the ticket was filed.
+func (t *TicketRecord) Age(now time.Time) (time.Duration, error) {
+	filed, err := ParseDatestamp(t.Datestamp)
+	if err != nil {
+		return 0, err
+	}
+	return now.Sub(filed), nil
+}
+
+// ParseDatestamp parses a datestamp of a record.
+func ParseDatestamp(datestamp string) (time.Time, error) {
+	return time.Parse(DateFormat, datestamp)
+}
+
 // sqlStore retrieves data from an SQL database.
 type sqlStore struct {
 	db             *sql.DB
@@ -121,11 +233,37 @@ type sqlStore struct {
 // Store defines Dragonwell SQL store interface.
 type Store interface {
 	AuditRecords(snapshot, auditname string) ([]*AuditRecord, error)
+	// SnapshotRecords fetches every audit result record of the snapshot,
+	// unlike AuditRecords without a limit.
+	SnapshotRecords(snapshot string) ([]*AuditRecord, error)
 	AuditCount(snapshot string) (map[string]int, error)
 	Snapshots() ([]string, string, string, error)
+	LatestSnapshot() (string, error)
 	AuditStats() (map[string][]*StatsRecord, *StatsRecord, error)
 	FixStats() (map[string][]*FixStatsRecord, error)
 	AuditTickets() ([]*TicketRecord, error)
+	Tickets(f *TicketFilter) ([]*TicketRecord, error)
+	TicketTrend() ([]*TicketTrendRecord, error)
+	// NetblockHistory fetches the newest records of the prefix, and whether
+	// older ones were left out.
+	NetblockHistory(prefix string) ([]*AuditRecord, bool, error)
+	FiringAlerts() (map[string]bool, error)
+	SetAlertFiring(key string, firing bool) error
+	Annotations() ([]*Annotation, error)
+	// SaveAnnotation adds the annotation if its ID is 0, and updates it
+	// otherwise. Updating an annotation which does not exist returns
+	// ErrNoAnnotation.
+	SaveAnnotation(a *Annotation) error
+	DeleteAnnotation(id int) error
+	CommentStore
+	// Ingest stages the batch, and commits the staged data of the producer
+	// and snapshot if the batch is complete.
+	Ingest(b *Batch) (*IngestResult, error)
+	// Ping verifies the connection to the database.
+	Ping() error
+	// Status reports the state of the connection pool and the result of
+	// preparing each statement.
+	Status() *StoreStatus
 	// Close releases resources associated with the store.
 	Close() error
 }
@@ -198,28 +336,137 @@ func (s *sqlStore) AuditRecords(snapshot, auditname string) ([]*AuditRecord, err
 		return nil, err
 	}
 	defer r.Close()
+	return scanAuditRecords(r)
+}
+
+// SnapshotRecords fetches all audit result records of every audit of the
+// snapshot.
+func (s *sqlStore) SnapshotRecords(snapshot string) ([]*AuditRecord, error) {
+	r, err := s.db.Query(fmt.Sprintf(snapshotSelect, auditTable), snapshot)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return scanAuditRecords(r)
+}
+
+// historyLimit bounds the number of records of a netblock history.
+const historyLimit = 10000
+
+// ParseHistoryPrefix parses the prefix of a netblock history. Prefixes
+// shorter than /8, or /16 for IPv6, are rejected since they would be
+// looked up by scanning every audit record.
+func ParseHistoryPrefix(prefix string) (*net.IPNet, error) {
+	_, ipNet, err := net.ParseCIDR(prefix)
+	if err != nil {
+		return nil, err
+	}
+	ones, bits := ipNet.Mask.Size()
+	min := 8
+	if bits == 128 {
+		min = 16
+	}
+	if ones < min {
+		return nil, fmt.Errorf("prefix %s is shorter than /%d", prefix, min)
+	}
+	return ipNet, nil
+}
+
+// NetblockHistory fetches audit result records of every snapshot and audit
+// for the given netblock prefix and all netblocks contained in it, newest
+// first and at most historyLimit of them. It reports whether older records
+// were left out.
+func (s *sqlStore) NetblockHistory(prefix string) ([]*AuditRecord, bool, error) {
+	ipNet, err := ParseHistoryPrefix(prefix)
+	if err != nil {
+		return nil, false, err
+	}
+	r, err := s.db.Query(fmt.Sprintf(netblockSelect, auditTable), likePattern(ipNet))
+	if err != nil {
+		return nil, false, err
+	}
+	defer r.Close()
+	// The LIKE pattern only narrows rows down to the leading octets, so
+	// drop anything that is not within the prefix before counting.
+	var rs []*AuditRecord
+	for r.Next() {
+		ar, err := scanAuditRecord(r)
+		if err != nil {
+			return nil, false, err
+		}
+		if !inPrefix(ipNet, ar.Netblock) {
+			continue
+		}
+		if len(rs) == historyLimit {
+			return rs, true, nil
+		}
+		rs = append(rs, ar)
+	}
+	return rs, false, r.Err()
+}
+
+// scanAuditRecords reads AuditRecords from rows selected with the columns of
+// auditSelect.
+func scanAuditRecords(r *sql.Rows) ([]*AuditRecord, error) {
+	var rs []*AuditRecord
+	for r.Next() {
+		ar, err := scanAuditRecord(r)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, ar)
+	}
+	return rs, r.Err()
+}
+
+// scanAuditRecord reads the AuditRecord of the current row.
+func scanAuditRecord(r *sql.Rows) (*AuditRecord, error) {
 	var netblock, auditName, auditCode, datestamp string
 	var tags, vlanID, building, gateway, attributes, childAttributes, expectedValue, correlates,
 		network, auditMsg, severity, state, tickets, fixState, fixMsg []byte
 	var id int
-	var rs []*AuditRecord
-	for r.Next() {
-		if err := r.Scan(&netblock, &tags, &vlanID, &building, &gateway, &attributes,
-			&childAttributes, &expectedValue, &network,
+	if err := r.Scan(&netblock, &tags, &vlanID, &building, &gateway, &attributes,
+		&childAttributes, &expectedValue, &network,
       &auditName, &auditCode, &correlates, &auditMsg, &severity, &state, &fixState, &fixMsg, &tickets,
-			&datestamp, &id); err != nil {
-			return nil, err
-		}
-		ipFields := strings.Split(netblock, "/")
-		superCode := strings.Split(auditCode, "_")
-		rs = append(rs, &AuditRecord{
+		&datestamp, &id); err != nil {
+		return nil, err
+	}
+	ipFields := strings.Split(netblock, "/")
+	if len(ipFields) != 2 {
+		return nil, fmt.Errorf("invalid netblock %q in record %d", netblock, id)
+	}
+	superCode := strings.Split(auditCode, "_")
+	return &AuditRecord{
       netblock, ipFields[0], ipFields[1], string(tags), string(vlanID), string(building), string(gateway),
       string(attributes), string(childAttributes), string(expectedValue), string(network), auditName,
       auditCode, superCode[0], string(correlates), string(auditMsg), string(severity), string(state), string(fixState), string(fixMsg), strings.Split(string(tickets), ","), datestamp, id,
-		})
+	}, nil
+}
+
+// likePattern returns a LIKE pattern matching the textual form of every
+// netblock within ipNet, which is at least a /8 or an IPv6 /16. Only whole
+// IPv4 octets are used, IPv6 netblocks are matched on their first group.
+func likePattern(ipNet *net.IPNet) string {
+	ones, _ := ipNet.Mask.Size()
+	if ip4 := ipNet.IP.To4(); ip4 != nil {
+		if ones == 32 {
+			return ip4.String() + "/%"
+		}
+		octets := strings.Split(ip4.String(), ".")
+		return strings.Join(octets[:ones/8], ".") + ".%"
 	}
+	return strings.Split(ipNet.IP.String(), ":")[0] + ":%"
+}
 
-	return rs, nil
+// inPrefix reports whether netblock is equal to or contained in ipNet.
+func inPrefix(ipNet *net.IPNet, netblock string) bool {
+	_, child, err := net.ParseCIDR(netblock)
+	if err != nil {
+		return false
+	}
+	ones, _ := ipNet.Mask.Size()
+	childOnes, _ := child.Mask.Size()
+	return childOnes >= ones && ipNet.Contains(child.IP)
 }
 
 // AuditCount fetches result count of audits by snapshot.
@@ -348,6 +595,12 @@ func (s *sqlStore) AuditTickets() ([]*TicketRecord, error) {
 		return nil, err
 	}
 	defer r.Close()
+	return scanTickets(r)
+}
+
+// scanTickets reads TicketRecords from rows selected with the columns of
+// ticketsSelect.
+func scanTickets(r *sql.Rows) ([]*TicketRecord, error) {
 	var summary, desc, auditName, auditCode, state, datestamp string
 	var ticketID int
 	var rs []*TicketRecord
@@ -360,7 +613,7 @@ func (s *sqlStore) AuditTickets() ([]*TicketRecord, error) {
 			summary, desc, auditName, auditCode, state, datestamp, ticketID,
 		})
 	}
-	return rs, nil
+	return rs, r.Err()
 }
 
 // Close releases the SQL database.
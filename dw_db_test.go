@@ -0,0 +1,55 @@
+package models
+
+import (
+	"net"
+	"testing"
+)
+
+func TestLikePattern(t *testing.T) {
+	tests := []struct {
+		prefix, want string
+	}{
+		{"10.0.0.0/8", "10.%"},
+		{"10.1.0.0/16", "10.1.%"},
+		{"10.1.2.0/23", "10.1.%"},
+		{"10.1.2.0/24", "10.1.2.%"},
+		{"10.1.2.3/32", "10.1.2.3/%"},
+		{"2001:db8::/32", "2001:%"},
+	}
+	for _, test := range tests {
+		ipNet, err := ParseHistoryPrefix(test.prefix)
+		if err != nil {
+			t.Errorf("ParseHistoryPrefix(%q) error: %v", test.prefix, err)
+			continue
+		}
+		if got := likePattern(ipNet); got != test.want {
+			t.Errorf("likePattern mismatch for %s, got: %q, want: %q", test.prefix, got, test.want)
+		}
+	}
+}
+
+func TestParseHistoryPrefix(t *testing.T) {
+	for _, prefix := range []string{"10.0.0.0", "0.0.0.0/0", "96.0.0.0/4", "2000::/3"} {
+		if _, err := ParseHistoryPrefix(prefix); err == nil {
+			t.Errorf("ParseHistoryPrefix(%q) succeeded, want error", prefix)
+		}
+	}
+}
+
+func TestInPrefix(t *testing.T) {
+	_, ipNet, _ := net.ParseCIDR("10.0.0.0/8")
+	tests := []struct {
+		netblock string
+		want     bool
+	}{
+		{"10.0.0.0/8", true},
+		{"10.20.0.0/16", true},
+		{"100.1.0.0/16", false},
+		{"10.0.0.0/7", false},
+	}
+	for _, test := range tests {
+		if got := inPrefix(ipNet, test.netblock); got != test.want {
+			t.Errorf("inPrefix mismatch for %s, got: %t, want: %t", test.netblock, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// StoreStatus reports the state of a Store for diagnostics.
+type StoreStatus struct {
+	OpenConnections int `json:"open_connections"`
+	InUse           int `json:"in_use"`
+	Idle            int `json:"idle"`
+	// Statements holds the result of preparing each statement of the store
+	// keyed on name, "ok" or the error.
+	Statements map[string]string `json:"statements"`
+}
+
+// LatestSnapshot fetches the newest audit datestamp, it is empty if there is
+// no audit data.
+func (s *sqlStore) LatestSnapshot() (string, error) {
+	var datestamp sql.NullString
+	if err := s.db.QueryRow(fmt.Sprintf(latestSnapshotSelect, auditTable)).Scan(&datestamp); err != nil {
+		return "", err
+	}
+	return datestamp.String, nil
+}
+
+// Ping verifies the connection to the database.
+func (s *sqlStore) Ping() error {
+	return s.db.Ping()
+}
+
+// Status reports the connection pool and prepares every statement of the
+// store, so that a missing table or column shows up by statement.
+func (s *sqlStore) Status() *StoreStatus {
+	stats := s.db.Stats()
+	status := &StoreStatus{
+		OpenConnections: stats.OpenConnections,
+		InUse:           stats.InUse,
+		Idle:            stats.Idle,
+		Statements:      make(map[string]string),
+	}
+	for name, query := range queries() {
+		stmt, err := s.db.Prepare(query)
+		if err != nil {
+			status.Statements[name] = err.Error()
+			continue
+		}
+		stmt.Close()
+		status.Statements[name] = "ok"
+	}
+	return status
+}
+
+// queries returns the SQL of the statements of the store keyed on name.
+func queries() map[string]string {
+	return map[string]string{
+		"audit":          fmt.Sprintf(auditSelect, auditTable),
+		"snapshot":       fmt.Sprintf(snapshotSelect, auditTable),
+		"audit_count":    fmt.Sprintf(auditCountSelect, auditTable),
+		"snapshots":      fmt.Sprintf(snapshotsSelect, auditTable),
+		"latest":         fmt.Sprintf(latestSnapshotSelect, auditTable),
+		"stats":          fmt.Sprintf(statsSelect, auditStatsTable),
+		"fix_stats":      fmt.Sprintf(fixStatsSelect, auditStatsTable),
+		"overall":        fmt.Sprintf(overallSelect, auditStatsTable),
+		"tickets":        fmt.Sprintf(ticketsSelect, ticketTable),
+		"ticket_trend":   fmt.Sprintf(ticketTrendSelect, ticketTable),
+		"netblock":       fmt.Sprintf(netblockSelect, auditTable),
+		"alert_state":    fmt.Sprintf(alertStateSelect, alertStateTable),
+		"alert_set":      fmt.Sprintf(alertStateInsert, alertStateTable),
+		"alert_clear":    fmt.Sprintf(alertStateDelete, alertStateTable),
+		"annotations":    fmt.Sprintf(annotationSelect, annotationTable),
+		"annotation_add": fmt.Sprintf(annotationInsert, annotationTable),
+		"annotation_set": fmt.Sprintf(annotationUpdate, annotationTable),
+		"annotation_del": fmt.Sprintf(annotationDelete, annotationTable),
+		"annotation_cnt": fmt.Sprintf(annotationCountSelect, annotationTable),
+		"batch":          fmt.Sprintf(batchSelect, batchTable),
+		"batch_add":      fmt.Sprintf(batchInsert, batchTable),
+		"stage_audit":    fmt.Sprintf(auditStageInsert, auditStageTable),
+		"stage_stats":    fmt.Sprintf(statsStageInsert, statsStageTable),
+		"comments":       fmt.Sprintf(commentSelect, commentTable),
+		"comment_count":  fmt.Sprintf(commentCountSelect, commentTable),
+		"comment_add":    fmt.Sprintf(commentInsert, commentTable),
+		"comment_del":    fmt.Sprintf(commentDelete, commentTable),
+		"comment_rev":    fmt.Sprintf(commentRevisionSelect, commentTable),
+	}
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"sort"
+)
+
+// FindingSpan is a period of consecutive snapshots in which a netblock
+// carried the same audit_code.
+type FindingSpan struct {
+	Netblock  string
+	AuditName string
+	AuditCode string
+	AuditMsg  string
+	FirstSeen string
+	LastSeen  string
+	// Cleared is the first snapshot after LastSeen without the finding, it
+	// is empty while the finding is still present in the newest snapshot.
+	Cleared string
+	Tickets []string
+}
+
+// findingKey identifies a finding across snapshots.
+type findingKey struct {
+	netblock, auditName, auditCode string
+}
+
+// BuildTimeline groups records of a netblock history into spans of the
+// given snapshots, ordered by the date each span started.
+func BuildTimeline(records []*AuditRecord, snapshots []string) []*FindingSpan {
+	dates := make([]string, len(snapshots))
+	copy(dates, snapshots)
+	sort.Strings(dates)
+
+	seen := make(map[findingKey]map[string]*AuditRecord)
+	var keys []findingKey
+	for _, ar := range records {
+		k := findingKey{ar.Netblock, ar.AuditName, ar.AuditCode}
+		if seen[k] == nil {
+			seen[k] = make(map[string]*AuditRecord)
+			keys = append(keys, k)
+		}
+		seen[k][ar.Datestamp] = ar
+	}
+
+	var spans []*FindingSpan
+	for _, k := range keys {
+		var span *FindingSpan
+		tickets := make(map[string]bool)
+		for _, d := range dates {
+			ar, ok := seen[k][d]
+			switch {
+			case ok && span == nil:
+				span = &FindingSpan{
+					Netblock:  k.netblock,
+					AuditName: k.auditName,
+					AuditCode: k.auditCode,
+					FirstSeen: d,
+				}
+				tickets = make(map[string]bool)
+				fallthrough
+			case ok:
+				span.LastSeen = d
+				span.AuditMsg = ar.AuditMsg
+				for _, t := range ar.Tickets {
+					if t != "" && !tickets[t] {
+						tickets[t] = true
+						span.Tickets = append(span.Tickets, t)
+					}
+				}
+			case span != nil:
+				span.Cleared = d
+				spans = append(spans, span)
+				span = nil
+			}
+		}
+		if span != nil {
+			spans = append(spans, span)
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool {
+		if spans[i].FirstSeen != spans[j].FirstSeen {
+			return spans[i].FirstSeen < spans[j].FirstSeen
+		}
+		if spans[i].Netblock != spans[j].Netblock {
+			return spans[i].Netblock < spans[j].Netblock
+		}
+		if spans[i].AuditName != spans[j].AuditName {
+			return spans[i].AuditName < spans[j].AuditName
+		}
+		return spans[i].AuditCode < spans[j].AuditCode
+	})
+	return spans
+}
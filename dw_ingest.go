@@ -0,0 +1,232 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// MaxBatchFindings bounds the number of findings of a batch.
+const MaxBatchFindings = 10000
+
+// ErrBatchConflict is returned when a batch ID is reused for another
+// producer or snapshot.
+var ErrBatchConflict = errors.New("batch ID already used for another producer or snapshot")
+
+// Finding is an audit finding delivered by an audit producer. It has the
+// fields of AuditRecord which are stored.
+type Finding struct {
+	Netblock        string   `json:"netblock"`
+	Tags            string   `json:"tags"`
+	VlanID          string   `json:"vlan_id"`
+	Building        string   `json:"building"`
+	Gateway         string   `json:"gateway"`
+	Attributes      string   `json:"attributes"`
+	ChildAttributes string   `json:"child_attributes"`
+	ExpectedValue   string   `json:"expected_value"`
+	Network         string   `json:"network"`
+	AuditName       string   `json:"audit_name"`
+	AuditCode       string   `json:"audit_code"`
+	Correlates      string   `json:"correlates"`
+	AuditMsg        string   `json:"audit_msg"`
+	Severity        string   `json:"severity"`
+	State           string   `json:"state"`
+	FixState        string   `json:"fix_state"`
+	FixMsg          string   `json:"fix_msg"`
+	Tickets         []string `json:"tickets"`
+}
+
+// BatchStats are the stats of an audit delivered by an audit producer.
+type BatchStats struct {
+	AuditName  string `json:"audit_name"`
+	ErrCount   int    `json:"err_count"`
+	WarnCount  int    `json:"warn_count"`
+	TotalCount int    `json:"total"`
+	// AutofixCount and FixedCount are set by audits with autofix.
+	AutofixCount *int `json:"autofix_count"`
+	FixedCount   *int `json:"fixed_count"`
+}
+
+// Batch is a delivery of findings and stats of a snapshot by an audit
+// producer. The snapshot is committed by the batch with Complete set.
+type Batch struct {
+	ID        string        `json:"batch_id"`
+	Producer  string        `json:"-"`
+	Datestamp string        `json:"snapshot"`
+	Findings  []*Finding    `json:"findings"`
+	Stats     []*BatchStats `json:"stats"`
+	Complete  bool          `json:"complete"`
+}
+
+// IngestResult is the outcome of the ingestion of a batch.
+type IngestResult struct {
+	BatchID string `json:"batch_id"`
+	// Duplicate is set if the batch was ingested before, in which case
+	// nothing was changed.
+	Duplicate bool `json:"duplicate"`
+	Findings  int  `json:"findings"`
+	Stats     int  `json:"stats"`
+	Committed bool `json:"committed"`
+}
+
+// Validate checks the batch against the shape of the audit tables.
+func (b *Batch) Validate() error {
+	if b.ID == "" || len(b.ID) > 64 {
+		return errors.New("batch_id must have 1 to 64 characters")
+	}
+	if _, err := ParseDatestamp(b.Datestamp); err != nil {
+		return fmt.Errorf("invalid snapshot %q", b.Datestamp)
+	}
+	if len(b.Findings) > MaxBatchFindings {
+		return fmt.Errorf("%d findings exceed the limit of %d per batch", len(b.Findings), MaxBatchFindings)
+	}
+	for i, f := range b.Findings {
+		if _, _, err := net.ParseCIDR(f.Netblock); err != nil {
+			return fmt.Errorf("finding %d: invalid netblock %q", i, f.Netblock)
+		}
+		if f.AuditName == "" || f.AuditCode == "" {
+			return fmt.Errorf("finding %d: missing audit_name or audit_code", i)
+		}
+		for _, t := range f.Tickets {
+			if t == "" || strings.Contains(t, ",") {
+				return fmt.Errorf("finding %d: invalid ticket %q", i, t)
+			}
+		}
+	}
+	seen := make(map[string]bool)
+	for i, st := range b.Stats {
+		switch {
+		case st.AuditName == "":
+			return fmt.Errorf("stats %d: missing audit_name", i)
+		case seen[st.AuditName]:
+			return fmt.Errorf("stats %d: duplicate audit_name %q", i, st.AuditName)
+		case st.ErrCount < 0 || st.WarnCount < 0 || st.ErrCount+st.WarnCount > st.TotalCount:
+			return fmt.Errorf("stats %d: counts out of range of total %d", i, st.TotalCount)
+		case st.AutofixCount != nil && st.FixedCount != nil && *st.FixedCount > *st.AutofixCount:
+			return fmt.Errorf("stats %d: fixed_count exceeds autofix_count", i)
+		}
+		seen[st.AuditName] = true
+	}
+	return nil
+}
+
+// AuditNames returns the audit names of the findings and stats of the batch.
+func (b *Batch) AuditNames() map[string]bool {
+	names := make(map[string]bool)
+	for _, f := range b.Findings {
+		names[f.AuditName] = true
+	}
+	for _, st := range b.Stats {
+		names[st.AuditName] = true
+	}
+	return names
+}
+
+// ratio formats count/total as the err_per and warn_per columns.
+func ratio(count, total int) string {
+	if total == 0 {
+		return "0"
+	}
+	return strconv.FormatFloat(float64(count)/float64(total), 'f', 4, 64)
+}
+
+// Ingest stages the findings and stats of the batch. The batch ID is
+// recorded in the same transaction, so that a batch delivered again is
+// reported as duplicate without changes. A complete batch replaces the data
+// of the audits staged by the producer for the snapshot with the staged data.
+func (s *sqlStore) Ingest(b *Batch) (*IngestResult, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	res := &IngestResult{BatchID: b.ID}
+	added, err := tx.Exec(fmt.Sprintf(batchInsert, batchTable), b.ID, b.Producer, b.Datestamp, b.Complete)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := added.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		var producer, datestamp string
+		if err := tx.QueryRow(fmt.Sprintf(batchSelect, batchTable), b.ID).Scan(&producer, &datestamp); err != nil {
+			return nil, err
+		}
+		if producer != b.Producer || datestamp != b.Datestamp {
+			return nil, ErrBatchConflict
+		}
+		res.Duplicate = true
+		return res, nil
+	}
+
+	stageAudit, err := tx.Prepare(fmt.Sprintf(auditStageInsert, auditStageTable))
+	if err != nil {
+		return nil, err
+	}
+	defer stageAudit.Close()
+	for _, f := range b.Findings {
+		if _, err := stageAudit.Exec(b.Producer, f.Netblock, f.Tags, f.VlanID, f.Building, f.Gateway, f.Attributes,
+			f.ChildAttributes, f.ExpectedValue, f.Network, f.AuditName,
+			f.AuditCode, f.Correlates, f.AuditMsg, f.Severity, f.State, f.FixState, f.FixMsg,
+			strings.Join(f.Tickets, ","), b.Datestamp); err != nil {
+			return nil, err
+		}
+		res.Findings++
+	}
+	stageStats, err := tx.Prepare(fmt.Sprintf(statsStageInsert, statsStageTable))
+	if err != nil {
+		return nil, err
+	}
+	defer stageStats.Close()
+	for _, st := range b.Stats {
+		if _, err := stageStats.Exec(b.Producer, st.AuditName, st.ErrCount, st.WarnCount,
+			ratio(st.ErrCount, st.TotalCount), ratio(st.WarnCount, st.TotalCount), st.TotalCount,
+			nullInt(st.AutofixCount), nullInt(st.FixedCount), b.Datestamp); err != nil {
+			return nil, err
+		}
+		res.Stats++
+	}
+
+	if b.Complete {
+		if err := commitStaged(tx, b.Producer, b.Datestamp); err != nil {
+			return nil, err
+		}
+		res.Committed = true
+	}
+	return res, tx.Commit()
+}
+
+// commitStaged replaces the audit data of the snapshot with the data staged
+// by the producer, for the audits the producer staged.
+func commitStaged(tx *sql.Tx, producer, datestamp string) error {
+	for _, q := range []struct {
+		query string
+		args  []interface{}
+	}{
+		{fmt.Sprintf(stagedDelete, auditTable, auditStageTable, statsStageTable),
+			[]interface{}{datestamp, datestamp, producer, datestamp, producer}},
+		{fmt.Sprintf(stagedDelete, auditStatsTable, auditStageTable, statsStageTable),
+			[]interface{}{datestamp, datestamp, producer, datestamp, producer}},
+		{fmt.Sprintf(auditStagedCopy, auditTable, auditStageTable), []interface{}{datestamp, producer}},
+		{fmt.Sprintf(statsStagedCopy, auditStatsTable, statsStageTable), []interface{}{datestamp, producer}},
+		{fmt.Sprintf(stageDelete, auditStageTable), []interface{}{datestamp, producer}},
+		{fmt.Sprintf(stageDelete, statsStageTable), []interface{}{datestamp, producer}},
+	} {
+		if _, err := tx.Exec(q.query, q.args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nullInt returns v as a nullable column value.
+func nullInt(v *int) sql.NullInt64 {
+	if v == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(*v), Valid: true}
+}
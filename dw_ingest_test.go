@@ -0,0 +1,72 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestBatchValidate(t *testing.T) {
+	finding := func() *Finding {
+		return &Finding{Netblock: "10.1.2.0/24", AuditName: "vlan", AuditCode: "E1", Tickets: []string{"b/1"}}
+	}
+	tests := []struct {
+		name   string
+		modify func(b *Batch)
+		want   string
+	}{
+		{"valid", func(b *Batch) {}, ""},
+		{"no id", func(b *Batch) { b.ID = "" }, "batch_id"},
+		{"long id", func(b *Batch) { b.ID = strings.Repeat("x", 65) }, "batch_id"},
+		{"snapshot", func(b *Batch) { b.Datestamp = "2016-13-01" }, "invalid snapshot"},
+		{"too many", func(b *Batch) { b.Findings = make([]*Finding, MaxBatchFindings+1) }, "exceed the limit"},
+		{"netblock", func(b *Batch) { b.Findings[0].Netblock = "10.1.2.0" }, "invalid netblock"},
+		{"audit code", func(b *Batch) { b.Findings[0].AuditCode = "" }, "missing audit_name"},
+		{"ticket", func(b *Batch) { b.Findings[0].Tickets = []string{"b/1,b/2"} }, "invalid ticket"},
+		{"stats name", func(b *Batch) { b.Stats[0].AuditName = "" }, "missing audit_name"},
+		{"stats duplicate", func(b *Batch) { b.Stats = append(b.Stats, &BatchStats{AuditName: "vlan", TotalCount: 1}) },
+			"duplicate audit_name"},
+		{"counts", func(b *Batch) { b.Stats[0].WarnCount = 10 }, "counts out of range"},
+		{"negative", func(b *Batch) { b.Stats[0].ErrCount = -1 }, "counts out of range"},
+		{"fixed", func(b *Batch) { b.Stats[0].AutofixCount, b.Stats[0].FixedCount = intPtr(1), intPtr(2) },
+			"fixed_count exceeds"},
+	}
+	for _, test := range tests {
+		b := &Batch{
+			ID:        "batch-1",
+			Datestamp: "2016-05-02",
+			Findings:  []*Finding{finding()},
+			Stats:     []*BatchStats{{AuditName: "vlan", ErrCount: 1, WarnCount: 2, TotalCount: 10}},
+		}
+		test.modify(b)
+		err := b.Validate()
+		switch {
+		case test.want == "" && err != nil:
+			t.Errorf("Validate error for %s: %v", test.name, err)
+		case test.want != "" && (err == nil || !strings.Contains(err.Error(), test.want)):
+			t.Errorf("Validate mismatch for %s, got: %v, want: %q", test.name, err, test.want)
+		}
+	}
+}
+
+func TestRatio(t *testing.T) {
+	tests := []struct {
+		count, total int
+		want         string
+	}{
+		{0, 0, "0"},
+		{5, 0, "0"},
+		{0, 10, "0.0000"},
+		{1, 3, "0.3333"},
+		{2, 3, "0.6667"},
+		{10, 10, "1.0000"},
+	}
+	for _, test := range tests {
+		if got := ratio(test.count, test.total); got != test.want {
+			t.Errorf("ratio mismatch for %d/%d, got: %s, want: %s", test.count, test.total, got, test.want)
+		}
+	}
+}
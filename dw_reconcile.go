@@ -0,0 +1,93 @@
+package models
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TicketRef is a whitelist ticket referenced by a finding.
+type TicketRef struct {
+	Finding *AuditRecord
+	// Ref is the reference as listed in AuditRecord.Tickets.
+	Ref string
+	// Ticket is the referenced ticket, nil if it does not exist.
+	Ticket *TicketRecord
+}
+
+// Reconciliation is the result of checking the whitelist tickets of findings
+// against the ticket table.
+type Reconciliation struct {
+	Snapshot string
+	// Missing lists references to tickets which do not exist.
+	Missing []*TicketRef
+	// Closed lists references to closed tickets.
+	Closed []*TicketRef
+	// Reopen lists findings whitelisted by a closed ticket and no open
+	// ticket, which should be reported as violations again.
+	Reopen []*AuditRecord
+	// Orphaned lists open tickets which no finding references anymore.
+	Orphaned []*TicketRecord
+}
+
+// Issues returns the number of problems found.
+func (r *Reconciliation) Issues() int {
+	return len(r.Missing) + len(r.Closed) + len(r.Reopen) + len(r.Orphaned)
+}
+
+// ParseTicketRef returns the ticket ID of a whitelist ticket reference such
+// as "b/1234" or "http://b/1234".
+func ParseTicketRef(ref string) (int, bool) {
+	ref = strings.TrimSpace(ref)
+	if i := strings.Index(ref, "://"); i >= 0 {
+		ref = ref[i+3:]
+	}
+	ref = strings.TrimPrefix(ref, "b/")
+	id, err := strconv.Atoi(ref)
+	return id, err == nil
+}
+
+// Reconcile checks the whitelist tickets of the findings of a snapshot
+// against tickets.
+func Reconcile(snapshot string, records []*AuditRecord, tickets []*TicketRecord) *Reconciliation {
+	byID := make(map[int]*TicketRecord)
+	for _, t := range tickets {
+		byID[t.TicketID] = t
+	}
+
+	rc := &Reconciliation{Snapshot: snapshot}
+	referenced := make(map[int]bool)
+	for _, ar := range records {
+		var refs, closed, missing int
+		for _, ref := range ar.Tickets {
+			if strings.TrimSpace(ref) == "" {
+				continue
+			}
+			refs++
+			id, ok := ParseTicketRef(ref)
+			t := byID[id]
+			switch {
+			case !ok || t == nil:
+				rc.Missing = append(rc.Missing, &TicketRef{ar, ref, nil})
+				missing++
+			case t.IsClosed():
+				rc.Closed = append(rc.Closed, &TicketRef{ar, ref, t})
+				closed++
+			}
+			if ok {
+				referenced[id] = true
+			}
+		}
+		if closed > 0 && closed+missing == refs {
+			rc.Reopen = append(rc.Reopen, ar)
+		}
+	}
+
+	for _, t := range tickets {
+		if !t.IsClosed() && !referenced[t.TicketID] {
+			rc.Orphaned = append(rc.Orphaned, t)
+		}
+	}
+	sort.Slice(rc.Orphaned, func(i, j int) bool { return rc.Orphaned[i].TicketID < rc.Orphaned[j].TicketID })
+	return rc
+}
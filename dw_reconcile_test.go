@@ -0,0 +1,67 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestParseTicketRef(t *testing.T) {
+	tests := []struct {
+		ref    string
+		want   int
+		wantOK bool
+	}{
+		{"b/1234", 1234, true},
+		{"http://b/1234", 1234, true},
+		{" 5678 ", 5678, true},
+		{"go/bug/1234", 0, false},
+		{"b/abc", 0, false},
+		{"", 0, false},
+	}
+	for _, test := range tests {
+		got, ok := ParseTicketRef(test.ref)
+		if ok != test.wantOK || (ok && got != test.want) {
+			t.Errorf("ParseTicketRef mismatch for %q, got: %d %t, want: %d %t", test.ref, got, ok, test.want, test.wantOK)
+		}
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	tickets := []*TicketRecord{
+		{TicketID: 1, State: "Fixed"},
+		{TicketID: 2, State: "Assigned"},
+		{TicketID: 3, State: "New"},
+		{TicketID: 4, State: "Obsolete"},
+	}
+	records := []*AuditRecord{
+		// whitelisted by a closed ticket only
+		{Netblock: "10.0.0.0/24", Tickets: []string{"b/1"}},
+		// a closed and an open ticket
+		{Netblock: "10.0.1.0/24", Tickets: []string{"b/1", "http://b/2"}},
+		// a closed and a missing ticket
+		{Netblock: "10.0.2.0/24", Tickets: []string{"b/4", "b/99"}},
+		// a reference which is not a ticket
+		{Netblock: "10.0.3.0/24", Tickets: []string{"go/bug"}},
+		{Netblock: "10.0.4.0/24", Tickets: []string{""}},
+	}
+	rc := Reconcile("2016-05-02", records, tickets)
+
+	var reopen []string
+	for _, ar := range rc.Reopen {
+		reopen = append(reopen, ar.Netblock)
+	}
+	if len(reopen) != 2 || reopen[0] != "10.0.0.0/24" || reopen[1] != "10.0.2.0/24" {
+		t.Errorf("Reopen mismatch, got: %v", reopen)
+	}
+	if len(rc.Closed) != 3 {
+		t.Errorf("Closed references mismatch, got: %d, want: 3", len(rc.Closed))
+	}
+	if len(rc.Missing) != 2 || rc.Missing[0].Ref != "b/99" || rc.Missing[1].Ref != "go/bug" {
+		t.Errorf("Missing references mismatch, got: %+v", rc.Missing)
+	}
+	if len(rc.Orphaned) != 1 || rc.Orphaned[0].TicketID != 3 {
+		t.Errorf("Orphaned mismatch, got: %+v", rc.Orphaned)
+	}
+	if rc.Issues() != 8 {
+		t.Errorf("Issues mismatch, got: %d, want: 8", rc.Issues())
+	}
+}
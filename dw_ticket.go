@@ -0,0 +1,105 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Ticket lifecycle states accepted by TicketFilter in addition to the
+// ticket states themselves.
+const (
+	TicketOpen   = "open"
+	TicketClosed = "closed"
+)
+
+// TicketFilter selects tickets. Empty fields match every ticket.
+type TicketFilter struct {
+	AuditName string
+	AuditCode string
+	// State is a ticket state, or TicketOpen or TicketClosed.
+	State string
+	// From and To bound the ticket datestamp, both inclusive.
+	From string
+	To   string
+}
+
+// TicketTrendRecord contains the number of tickets filed on a datestamp which
+// are open and closed now. Tickets do not record when they were closed, so
+// the states as of the datestamp are not known.
+type TicketTrendRecord struct {
+	Datestamp string
+	Open      int
+	Closed    int
+}
+
+// Tickets fetches the tickets matching the filter.
+func (s *sqlStore) Tickets(f *TicketFilter) ([]*TicketRecord, error) {
+	var conds []string
+	var args []interface{}
+	for _, c := range []struct{ cond, value string }{
+		{"audit_name=?", f.AuditName},
+		{"audit_code=?", f.AuditCode},
+		{"datestamp>=?", f.From},
+		{"datestamp<=?", f.To},
+	} {
+		if c.value != "" {
+			conds = append(conds, c.cond)
+			args = append(args, c.value)
+		}
+	}
+	lifecycle := f.State == TicketOpen || f.State == TicketClosed
+	if f.State != "" && !lifecycle {
+		conds = append(conds, "state=?")
+		args = append(args, f.State)
+	}
+
+	query := fmt.Sprintf(ticketsSelect, ticketTable)
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	r, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	tickets, err := scanTickets(r)
+	if err != nil || !lifecycle {
+		return tickets, err
+	}
+
+	var rs []*TicketRecord
+	for _, t := range tickets {
+		if t.IsClosed() == (f.State == TicketClosed) {
+			rs = append(rs, t)
+		}
+	}
+	return rs, nil
+}
+
+// TicketTrend fetches the number of tickets filed per datestamp by their
+// current state.
+func (s *sqlStore) TicketTrend() ([]*TicketTrendRecord, error) {
+	r, err := s.db.Query(fmt.Sprintf(ticketTrendSelect, ticketTable))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var datestamp, state string
+	var count int
+	var rs []*TicketTrendRecord
+	for r.Next() {
+		if err := r.Scan(&datestamp, &state, &count); err != nil {
+			return nil, err
+		}
+		if len(rs) == 0 || rs[len(rs)-1].Datestamp != datestamp {
+			rs = append(rs, &TicketTrendRecord{Datestamp: datestamp})
+		}
+		t := TicketRecord{State: state}
+		if t.IsClosed() {
+			rs[len(rs)-1].Closed += count
+		} else {
+			rs[len(rs)-1].Open += count
+		}
+	}
+	return rs, r.Err()
+}
@@ -0,0 +1,109 @@
+// Package link builds links to external systems, such as IPDB and the ticket
+// tracker, from the URL templates configured per field.
+package link
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	".../go/models"
+)
+
+// Fields of the configured link templates.
+const (
+	Netblock = "netblock"
+	Ticket   = "ticket"
+	Building = "building"
+	Vlan     = "vlan"
+	Gateway  = "gateway"
+)
+
+// placeholder is replaced by the query escaped field value in templates.
+const placeholder = "{value}"
+
+// Link is an external link of a field value.
+type Link struct {
+	Field string `json:"field"`
+	Value string `json:"value"`
+	URL   string `json:"url"`
+}
+
+// Registry holds the URL templates keyed on field.
+type Registry struct {
+	templates map[string]string
+}
+
+// New validates the URL templates. Templates are absolute http or https URLs
+// containing {value}, such as "http://b/{value}".
+func New(templates map[string]string) (*Registry, error) {
+	r := &Registry{templates: make(map[string]string)}
+	for field, t := range templates {
+		if !strings.Contains(t, placeholder) {
+			return nil, fmt.Errorf("link template of %s lacks %s: %q", field, placeholder, t)
+		}
+		u, err := url.Parse(strings.Replace(t, placeholder, "value", -1))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("link template of %s is not an http URL: %q", field, t)
+		}
+		r.templates[field] = t
+	}
+	return r, nil
+}
+
+// URL returns the link of the field value, or "" if the field has no
+// template or the value is empty. Ticket values are whitelist references
+// such as "b/1234", linked by their ticket ID. Tickets without a template
+// or which are not references keep the plain http://ticket link. A nil
+// Registry has no templates.
+func (r *Registry) URL(field, value string) string {
+	if value == "" {
+		return ""
+	}
+	var t string
+	if r != nil {
+		t = r.templates[field]
+	}
+	if field == Ticket {
+		id, ok := models.ParseTicketRef(value)
+		if t == "" || !ok {
+			return "http://" + value
+		}
+		value = strconv.Itoa(id)
+	}
+	if t == "" {
+		return ""
+	}
+	return strings.Replace(t, placeholder, url.QueryEscape(value), -1)
+}
+
+// add appends the link of the field value to links if there is one.
+func (r *Registry) add(links []*Link, field, value string) []*Link {
+	if u := r.URL(field, value); u != "" {
+		links = append(links, &Link{field, value, u})
+	}
+	return links
+}
+
+// Record returns the links of the fields of an audit record.
+func (r *Registry) Record(ar *models.AuditRecord) []*Link {
+	var links []*Link
+	links = r.add(links, Netblock, ar.Netblock)
+	links = r.add(links, Building, ar.Building)
+	links = r.add(links, Vlan, ar.VlanID)
+	links = r.add(links, Gateway, ar.Gateway)
+	for _, t := range ar.Tickets {
+		links = r.add(links, Ticket, t)
+	}
+	return links
+}
+
+// Span returns the links of the netblock and tickets of a finding span.
+func (r *Registry) Span(s *models.FindingSpan) []*Link {
+	links := r.add(nil, Netblock, s.Netblock)
+	for _, t := range s.Tickets {
+		links = r.add(links, Ticket, t)
+	}
+	return links
+}
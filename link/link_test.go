@@ -0,0 +1,66 @@
+package link
+
+import (
+	"testing"
+
+	".../go/models"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name      string
+		templates map[string]string
+		wantErr   bool
+	}{
+		{name: "valid", templates: map[string]string{Netblock: "http://go/netblocks/?ip_address={value}"}},
+		{name: "no placeholder", templates: map[string]string{Ticket: "http://b/"}, wantErr: true},
+		{name: "relative", templates: map[string]string{Ticket: "/tickets/{value}"}, wantErr: true},
+		{name: "scheme", templates: map[string]string{Ticket: "javascript:alert({value})"}, wantErr: true},
+	}
+	for _, test := range tests {
+		_, err := New(test.templates)
+		if (err != nil) != test.wantErr {
+			t.Errorf("Error mismatch for test: %s, got: %v, want error: %t", test.name, err, test.wantErr)
+		}
+	}
+}
+
+func TestURL(t *testing.T) {
+	r, err := New(map[string]string{
+		Netblock: "http://go/netblocks/?ip_address={value}",
+		Ticket:   "https://tickets.example.com/{value}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		field, value, want string
+	}{
+		{Netblock, "10.1.0.0/16", "http://go/netblocks/?ip_address=10.1.0.0%2F16"},
+		{Ticket, "b/1234", "https://tickets.example.com/1234"},
+		{Ticket, "http://b/1234", "https://tickets.example.com/1234"},
+		{Ticket, "5678", "https://tickets.example.com/5678"},
+		{Ticket, "go/outage-123", "http://go/outage-123"},
+		{Ticket, "", ""},
+		{Building, "US-MTV-40", ""},
+		{Netblock, "", ""},
+	}
+	for _, test := range tests {
+		if got := r.URL(test.field, test.value); got != test.want {
+			t.Errorf("URL mismatch for %s %q, got: %q, want: %q", test.field, test.value, got, test.want)
+		}
+	}
+
+	var none *Registry
+	if got, want := none.URL(Ticket, "b/1234"), "http://b/1234"; got != want {
+		t.Errorf("URL mismatch without templates, got: %q, want: %q", got, want)
+	}
+	if got := none.URL(Netblock, "10.1.0.0/16"); got != "" {
+		t.Errorf("URL mismatch without templates, got: %q, want: \"\"", got)
+	}
+
+	links := r.Record(&models.AuditRecord{Netblock: "10.1.0.0/16", Building: "US-MTV-40", Tickets: []string{"b/1", "b/2", ""}})
+	if len(links) != 3 || links[0].Field != Netblock || links[2].Value != "b/2" {
+		t.Errorf("Record links mismatch, got: %+v", links)
+	}
+}
@@ -0,0 +1,80 @@
+// Package mail sends Dragonwell notification mails.
+package mail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+)
+
+// Message is a mail with a plain text and an optional HTML body.
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Sender delivers mail messages.
+type Sender interface {
+	Send(m *Message) error
+}
+
+// SMTPSender delivers messages through an SMTP relay.
+type SMTPSender struct {
+	// Addr is the host:port of the relay.
+	Addr string
+	// Auth is used if the relay requires authentication.
+	Auth smtp.Auth
+}
+
+// Send delivers m through the relay.
+func (s *SMTPSender) Send(m *Message) error {
+	if len(m.To) == 0 {
+		return errors.New("mail: no recipients")
+	}
+	b, err := m.bytes()
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(s.Addr, s.Auth, m.From, m.To, b)
+}
+
+// bytes returns the RFC 822 encoding of the message.
+func (m *Message) bytes() ([]byte, error) {
+	b := bytes.NewBuffer([]byte{})
+	fmt.Fprintf(b, "From: %s\r\n", m.From)
+	fmt.Fprintf(b, "To: %s\r\n", strings.Join(m.To, ", "))
+	fmt.Fprintf(b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	if m.HTML == "" {
+		b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+		b.WriteString(m.Text)
+		return b.Bytes(), nil
+	}
+
+	w := multipart.NewWriter(b)
+	fmt.Fprintf(b, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", w.Boundary())
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", m.Text},
+		{"text/html; charset=utf-8", m.HTML},
+	} {
+		pw, err := w.CreatePart(textproto.MIMEHeader{"Content-Type": {part.contentType}})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := pw.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -4,46 +4,86 @@ package render
 import (
 	"appengine"
 	"appengine/user"
-	"html/template"
 	"net/http"
-	"path"
+	"time"
 
+	".../go/config"
 	".../go/models"
+	".../go/slo"
+	".../go/static"
 )
 
-// templateDir is the directory where HTML templates are stored.
-const templateDir = ".../go/templates"
-
 // depreAudits is the list of deprecated audits
 var depreAudits = []string{"al_dns", "_netmgt"}
 
-// These are the templates which can be rendered.
-var reportTemplate, chartTemplate, fixTemplate, ticketTemplate *template.Template
-
-// loadTemplate returns a parsed template containing the given
-// template file with the layout as the base template.  Note that the
-// layout filenames are formatted in a specific manner.  This function
-// will panic if the templates cannot be parsed.
-func loadTemplate(layoutName, templateName string) *template.Template {
-	return template.Must(
-		template.ParseFiles(
-			path.Join(templateDir, "_"+layoutName+"_layout.html"),
-			path.Join(templateDir, templateName+".html")))
+// overallStats is the compliance of the corp_reports row.
+type overallStats struct {
+	ErrCount  int
+	Compliant int
+}
+
+// chartData is the data of the AuditChart page.
+type chartData struct {
+	AuditStats       map[string][]*models.StatsRecord
+	DeprecatedAudits []string
+	OverallStats     overallStats
+	// Annotations are the chart annotations keyed on audit name.
+	Annotations map[string][]*models.Annotation
+	Objectives  []*slo.Status
 }
 
-// init reads and compiles the templates in templateDir.
+// fixChartData is the data of the FixChart page.
+type fixChartData struct {
+	FixStats map[string][]*models.FixStatsRecord
+	// Annotations are the chart annotations keyed on audit name.
+	Annotations map[string][]*models.Annotation
+}
+
+// reportData is the data of the AuditReport page.
+type reportData struct {
+	AuditRecords      []*models.AuditRecord
+	AuditNames        []string
+	AuditNameSelected string
+	AuditCodeSelected string
+	AuditCount        map[string]int
+	MinDate           string
+	MaxDate           string
+	Snapshots         []string
+	SnapshotSelected  string
+	// CommentCounts are the number of comments keyed on finding key.
+	CommentCounts map[string]int
+	UserName      string
+}
+
+// init loads the link templates, compiles and validates the templates, and
+// sets handler functions for URLs.
 func init() {
-	reportTemplate = loadTemplate("main", "auditreport")
-	chartTemplate = loadTemplate("main", "auditchart")
-	fixTemplate = loadTemplate("main", "fixchart")
-	ticketTemplate = loadTemplate("main", "auditticket")
+	links = mustLoadLinks()
+	templates = mustLoadTemplates()
+	http.Handle(static.URLPrefix, static.Handler())
 	http.HandleFunc("/", auditChartHandler)
-	http.HandleFunc("/auditreport/", auditReportHandler)
+	http.HandleFunc("/auditreport/", cacheable(auditReportHandler))
 	http.HandleFunc("/auditticket/", auditTicketHandler)
-	http.HandleFunc("/fixchart/", fixChartHandler)
+	http.HandleFunc("/fixchart/", cacheable(fixChartHandler))
+	http.HandleFunc("/chart/", cacheable(chartHandler))
+	http.HandleFunc("/netblock/", cacheable(netblockHandler))
+	http.HandleFunc("/api/netblock/", cacheable(netblockAPIHandler))
+	http.HandleFunc("/reconcile/", reconcileHandler)
+	http.HandleFunc("/annotations/", annotationsHandler)
+	http.HandleFunc("/api/annotations/", annotationAPIHandler)
+	http.HandleFunc("/api/ingest", ingestHandler)
+	http.HandleFunc("/api/comments/", commentAPIHandler)
+	http.HandleFunc("/digest/", digestHandler)
+	http.HandleFunc("/healthz", healthzHandler)
+	http.HandleFunc("/readyz", readyzHandler)
+	http.HandleFunc("/debug/status", debugStatusHandler)
+	http.HandleFunc("/tasks/alerts", alertTaskHandler)
+	http.HandleFunc("/tasks/digest", digestTaskHandler)
+	http.HandleFunc("/tasks/reconcile", reconcileTaskHandler)
 }
 
-// auditChartHandler renders the AuditChart page of the site.
+// auditChartHandler renders the AuditChart page of the site. It is not
+// cacheable, the objective projections change with the current time.
 func auditChartHandler(w http.ResponseWriter, req *http.Request) {
 	c := appengine.NewContext(req)
 	stats := make(map[string][]*models.StatsRecord)
@@ -54,32 +94,37 @@ func auditChartHandler(w http.ResponseWriter, req *http.Request) {
 	}
 	defer store.Close()
 
-	var overallStats *models.StatsRecord
-	stats, overallStats, err = store.AuditStats()
+	var overall *models.StatsRecord
+	stats, overall, err = store.AuditStats()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		c.Infof("Error on audit stats query: %s", err.Error())
 	}
 	c.Infof("stats len: %d", len(stats))
-	templateData := struct {
-		AuditStats       map[string][]*models.StatsRecord
-		DeprecatedAudits []string
-		OverallStats     struct {
-			ErrCount  int
-			Compliant int
-		}
-	}{
+	annotations, err := store.Annotations()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var auditNames []string
+	for k := range stats {
+		auditNames = append(auditNames, k)
+	}
+	objectives, err := slo.ObjectivesFromConfig(config.Get().Objectives)
+	if err != nil {
+		c.Errorf("Invalid objectives: %v", err)
+	}
+	templateData := chartData{
+		Annotations:      models.AnnotationsByAudit(annotations, auditNames),
+		Objectives:       slo.Evaluate(objectives, stats, time.Now()),
 		AuditStats:       stats,
 		DeprecatedAudits: depreAudits,
-		OverallStats: struct {
-			ErrCount  int
-			Compliant int
-		}{
-			ErrCount:  overallStats.ErrCount,
-			Compliant: overallStats.TotalCount - overallStats.ErrCount,
+		OverallStats: overallStats{
+			ErrCount:  overall.ErrCount,
+			Compliant: overall.TotalCount - overall.ErrCount,
 		},
 	}
-	if err := renderLayout(c, w, chartTemplate, templateData); err != nil {
+	if err := renderLayout(c, w, "auditchart", templateData); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -101,8 +146,21 @@ func fixChartHandler(w http.ResponseWriter, req *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 	c.Infof("fixStats len: %d", len(fixStats))
+	annotations, err := store.Annotations()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var auditNames []string
+	for k := range fixStats {
+		auditNames = append(auditNames, k)
+	}
+	templateData := fixChartData{
+		FixStats:    fixStats,
+		Annotations: models.AnnotationsByAudit(annotations, auditNames),
+	}
 
-	if err := renderLayout(c, w, fixTemplate, fixStats); err != nil {
+	if err := renderLayout(c, w, "fixchart", templateData); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -152,18 +210,18 @@ func auditReportHandler(w http.ResponseWriter, req *http.Request) {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 		}
 	}
+	commentCounts, err := store.CommentCounts(auditNameSelected)
+	if err != nil {
+		c.Errorf("CommentCounts error: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var userName string
+	if u := user.Current(c); u != nil {
+		userName = u.String()
+	}
 
-	templateData := struct {
-		AuditRecords      []*models.AuditRecord
-		AuditNames        []string
-		AuditNameSelected string
-		AuditCodeSelected string
-		AuditCount        map[string]int
-		MinDate           string
-		MaxDate           string
-		Snapshots         []string
-		SnapshotSelected  string
-	}{
+	templateData := reportData{
 		AuditRecords:      auditRecords,
 		AuditNames:        auditNames,
 		AuditNameSelected: auditNameSelected,
@@ -173,38 +231,49 @@ func auditReportHandler(w http.ResponseWriter, req *http.Request) {
 		MaxDate:           maxDate,
 		Snapshots:         snapshots,
 		SnapshotSelected:  snapshotSelected,
+		CommentCounts:     commentCounts,
+		UserName:          userName,
 	}
-	if err := renderLayout(c, w, reportTemplate, templateData); err != nil {
+	if err := renderLayout(c, w, "auditreport", templateData); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 }
 
-// auditTicketHandler renders the ticket page of the site.
+// auditTicketHandler renders the ticket page of the site. It is not
+// cacheable, ticket states and ages change without a new snapshot.
 func auditTicketHandler(w http.ResponseWriter, req *http.Request) {
 	c := appengine.NewContext(req)
 	store, err := models.NewSqlStore(c)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 	defer store.Close()
 	c.Infof("connected to DB")
 
-	var auditTickets []*models.TicketRecord
-	auditTickets, err = store.AuditTickets()
+	filter := ticketFilter(req)
+	auditTickets, err := store.Tickets(&filter)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 	c.Infof("tickets len: %d", len(auditTickets))
+	trend, err := store.TicketTrend()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-	if err := renderLayout(c, w, ticketTemplate, auditTickets); err != nil {
+	data := newTicketData(auditTickets, filter, trend, &config.Get().Tickets, time.Now())
+	if err := renderLayout(c, w, "auditticket", data); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 }
 
-// renderLayout renders the template with the data using the main layout.
-func renderLayout(c appengine.Context, w http.ResponseWriter, template *template.Template, data interface{}) error {
+// renderLayout renders the named page with the data using the main layout.
+func renderLayout(c appengine.Context, w http.ResponseWriter, name string, data interface{}) error {
 
 	var userName string
 	if u := user.Current(c); u != nil {
@@ -218,5 +287,11 @@ func renderLayout(c appengine.Context, w http.ResponseWriter, template *template
 		ContentData: data,
 	}
 
-	return template.ExecuteTemplate(w, "layout", headerInfo)
+	b, err := executePage(name, headerInfo)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Security-Policy", static.ContentSecurityPolicy(b.Bytes()))
+	_, err = b.WriteTo(w)
+	return err
 }
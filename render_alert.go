@@ -0,0 +1,70 @@
+package render
+
+import (
+	"fmt"
+	"net/http"
+
+	"appengine"
+	"appengine/urlfetch"
+
+	".../go/alert"
+	".../go/config"
+	".../go/mail"
+	".../go/models"
+)
+
+// notifiers returns the alert notifiers of the site configuration.
+func notifiers(c appengine.Context, cfg *config.AlertConfig) []alert.Notifier {
+	var ns []alert.Notifier
+	for _, wh := range cfg.Webhooks {
+		ns = append(ns, &alert.WebhookNotifier{URL: wh.URL, Client: urlfetch.Client(c)})
+	}
+	if cfg.Email != nil {
+		ns = append(ns, &alert.EmailNotifier{
+			Sender: &mail.SMTPSender{Addr: cfg.Email.SMTPAddr},
+			From:   cfg.Email.From,
+			To:     cfg.Email.To,
+		})
+	}
+	return ns
+}
+
+// alertTaskHandler evaluates the alerting rules on the audit stats and
+// notifies about new alerts. It is run by cron after snapshots are loaded.
+func alertTaskHandler(w http.ResponseWriter, req *http.Request) {
+	c := appengine.NewContext(req)
+	cfg := config.Get().Alerts
+	rules, err := alert.RulesFromConfig(cfg.Rules)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	store, err := models.NewSqlStore(c)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer store.Close()
+
+	stats, _, err := store.AuditStats()
+	if err != nil {
+		c.Infof("Error on audit stats query: %s", err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	alerts, err := alert.Evaluate(rules, stats)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	d := &alert.Dispatcher{Notifiers: notifiers(c, &cfg), State: store}
+	sent, err := d.Dispatch(alerts)
+	if err != nil {
+		c.Errorf("Dispatch error: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	c.Infof("alerts firing: %d, sent: %d", len(alerts), len(sent))
+	fmt.Fprintf(w, "alerts firing: %d, sent: %d\n", len(alerts), len(sent))
+}
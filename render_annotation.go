@@ -0,0 +1,160 @@
+package render
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"appengine"
+	"appengine/user"
+
+	".../go/models"
+)
+
+// annotationsData is the data of the Annotations page.
+type annotationsData struct {
+	Annotations []*models.Annotation
+	AuditNames  []string
+}
+
+// annotationsHandler renders the page listing and editing the chart
+// annotations.
+func annotationsHandler(w http.ResponseWriter, req *http.Request) {
+	c := appengine.NewContext(req)
+	store, err := models.NewSqlStore(c)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer store.Close()
+
+	annotations, err := store.Annotations()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	stats, _, err := store.AuditStats()
+	if err != nil {
+		c.Infof("Error on audit stats query: %s", err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	data := &annotationsData{Annotations: annotations}
+	for auditName := range stats {
+		data.AuditNames = append(data.AuditNames, auditName)
+	}
+	sort.Strings(data.AuditNames)
+	if err := renderLayout(c, w, "annotations", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// requireJSON replies with an error unless the request body is json.
+// Requiring json keeps cross-site forms from posting to the API, since
+// browsers only send it cross-site after a CORS preflight.
+func requireJSON(w http.ResponseWriter, req *http.Request) bool {
+	if ct, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type")); ct != "application/json" {
+		http.Error(w, "content type must be application/json", http.StatusUnsupportedMediaType)
+		return false
+	}
+	return true
+}
+
+// annotationAPIHandler serves the annotations as json. GET and POST on
+// /api/annotations/ list and add annotations, PUT and DELETE on
+// /api/annotations/{id} update and delete one. Listings are filtered on the
+// auditname parameter. Changes require a signed-in user.
+func annotationAPIHandler(w http.ResponseWriter, req *http.Request) {
+	c := appengine.NewContext(req)
+	var id int
+	if s := strings.TrimPrefix(req.URL.Path, "/api/annotations/"); s != "" {
+		var err error
+		if id, err = strconv.Atoi(s); err != nil || id <= 0 {
+			http.Error(w, "invalid annotation id "+s, http.StatusBadRequest)
+			return
+		}
+	}
+	collection := req.Method == "GET" || req.Method == "POST"
+	if collection != (id == 0) || (!collection && req.Method != "PUT" && req.Method != "DELETE") {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if req.Method != "GET" && user.Current(c) == nil {
+		http.Error(w, "sign in required", http.StatusUnauthorized)
+		return
+	}
+
+	store, err := models.NewSqlStore(c)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer store.Close()
+
+	var resp interface{}
+	switch req.Method {
+	case "GET":
+		annotations, err := store.Annotations()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if auditName := req.URL.Query().Get("auditname"); auditName != "" {
+			annotations = models.AnnotationsByAudit(annotations, []string{auditName})[auditName]
+		}
+		if annotations == nil {
+			annotations = []*models.Annotation{}
+		}
+		resp = annotations
+	case "POST", "PUT":
+		if !requireJSON(w, req) {
+			return
+		}
+		a := &models.Annotation{}
+		if err := json.NewDecoder(req.Body).Decode(a); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		a.ID = id
+		if err := a.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := store.SaveAnnotation(a); err == models.ErrNoAnnotation {
+			http.NotFound(w, req)
+			return
+		} else if err != nil {
+			c.Errorf("SaveAnnotation error: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		c.Infof("annotation %d saved by %s", a.ID, user.Current(c))
+		expireLatest()
+		resp = a
+	case "DELETE":
+		if err := store.DeleteAnnotation(id); err == models.ErrNoAnnotation {
+			http.NotFound(w, req)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		c.Infof("annotation %d deleted by %s", id, user.Current(c))
+		expireLatest()
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	js, err := json.Marshal(resp)
+	if err != nil {
+		c.Infof("Error marshal json response: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
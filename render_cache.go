@@ -0,0 +1,207 @@
+package render
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"appengine"
+	"appengine/user"
+
+	".../go/models"
+)
+
+// snapshotCacheTTL bounds how long the newest datestamp is reused before the
+// store is queried again.
+const snapshotCacheTTL = time.Minute
+
+// latest caches the newest datestamp and the revision of the data users
+// edit, so that conditional requests are answered without opening a store.
+var latest struct {
+	sync.Mutex
+	datestamp string
+	revision  string
+	// revised is when the revision was first seen by this instance.
+	revised time.Time
+	fetched time.Time
+}
+
+// latestSnapshot returns the newest datestamp of the audit data, the
+// revision of the chart annotations and finding comments, and when the
+// revision was first seen.
+func latestSnapshot(c appengine.Context) (string, string, time.Time, error) {
+	latest.Lock()
+	defer latest.Unlock()
+	if time.Since(latest.fetched) < snapshotCacheTTL {
+		return latest.datestamp, latest.revision, latest.revised, nil
+	}
+	store, err := models.NewSqlStore(c)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	defer store.Close()
+	datestamp, err := store.LatestSnapshot()
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	annotations, err := store.Annotations()
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	comments, err := store.CommentsRevision()
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	revision := annotationsRevision(annotations) + ":" + comments
+	if revision != latest.revision {
+		latest.revised = time.Now()
+	}
+	latest.datestamp = datestamp
+	latest.revision = revision
+	latest.fetched = time.Now()
+	return latest.datestamp, latest.revision, latest.revised, nil
+}
+
+// lastModified returns the time of the response of a snapshot whose edited
+// data was revised at revised, the later of both rounded up to the second
+// of HTTP dates. Instances which see the revision later send a later time,
+// which only costs a full response.
+func lastModified(datestamp string, revised time.Time) time.Time {
+	modified, err := models.ParseDatestamp(datestamp)
+	if err != nil {
+		return time.Time{}
+	}
+	if revised.After(modified) {
+		modified = revised
+	}
+	if t := modified.Truncate(time.Second); t.Before(modified) {
+		modified = t.Add(time.Second)
+	}
+	return modified
+}
+
+// expireLatest drops the cached validators after a change of annotations or
+// comments. Other instances pick up the change within snapshotCacheTTL.
+func expireLatest() {
+	latest.Lock()
+	defer latest.Unlock()
+	latest.fetched = time.Time{}
+}
+
+// annotationsRevision returns a digest of the annotations.
+func annotationsRevision(annotations []*models.Annotation) string {
+	h := sha256.New()
+	for _, a := range annotations {
+		for _, s := range []string{strconv.Itoa(a.ID), a.Datestamp, a.AuditName, a.Title, a.Link} {
+			h.Write([]byte(s))
+			h.Write([]byte{0})
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// etag derives the entity tag of a response from the snapshot, the revision
+// of the data users edit, the build, the user the page is rendered for and
+// the request URL.
+func etag(datestamp, revision, userName string, req *http.Request) string {
+	query := req.URL.Query()
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, s := range []string{datestamp, revision, buildVersion, userName, req.URL.Path} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	for _, k := range keys {
+		h.Write([]byte(k + "=" + strings.Join(query[k], ",")))
+		h.Write([]byte{0})
+	}
+	return `W/"` + hex.EncodeToString(h.Sum(nil))[:20] + `"`
+}
+
+// etagMatch reports whether the If-None-Match header matches tag. Weak
+// comparison is used, as for GET requests.
+func etagMatch(ifNoneMatch, tag string) bool {
+	tag = strings.TrimPrefix(tag, "W/")
+	for _, t := range strings.Split(ifNoneMatch, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified reports whether the client copy of the response is current.
+func notModified(req *http.Request, tag string, modified time.Time) bool {
+	if inm := req.Header.Get("If-None-Match"); inm != "" {
+		return etagMatch(inm, tag)
+	}
+	if ims := req.Header.Get("If-Modified-Since"); ims != "" && !modified.IsZero() {
+		t, err := http.ParseTime(ims)
+		return err == nil && !modified.After(t)
+	}
+	return false
+}
+
+// cacheWriter drops the validators of error responses, so that errors are
+// never revalidated as current.
+type cacheWriter struct {
+	http.ResponseWriter
+}
+
+// WriteHeader removes the validators unless the response is successful.
+func (w cacheWriter) WriteHeader(code int) {
+	if code != http.StatusOK {
+		w.Header().Del("ETag")
+		w.Header().Del("Last-Modified")
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// cacheable wraps a handler of snapshot data with ETag and Last-Modified
+// validators, and answers conditional requests for unchanged data with 304.
+// Responses are revalidated on every use since the data changes when a new
+// datestamp is loaded or annotations and comments are edited. Pages which
+// depend on the current time must not be wrapped.
+func cacheable(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != "GET" && req.Method != "HEAD" {
+			h(w, req)
+			return
+		}
+		c := appengine.NewContext(req)
+		datestamp, revision, revised, err := latestSnapshot(c)
+		if err != nil || datestamp == "" {
+			c.Warningf("No snapshot for cache validators: %v", err)
+			h(w, req)
+			return
+		}
+		var userName string
+		if u := user.Current(c); u != nil {
+			userName = u.String()
+		}
+		tag := etag(datestamp, revision, userName, req)
+		modified := lastModified(datestamp, revised)
+
+		w.Header().Set("ETag", tag)
+		w.Header().Set("Cache-Control", "private, no-cache")
+		if !modified.IsZero() {
+			w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+		}
+		if notModified(req, tag, modified) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		h(cacheWriter{w}, req)
+	}
+}
@@ -0,0 +1,44 @@
+package render
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestLastModified(t *testing.T) {
+	day := time.Date(2016, 5, 2, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		revised time.Time
+		want    time.Time
+	}{
+		{"unrevised", time.Time{}, day},
+		{"revised before", day.Add(-time.Hour), day},
+		{"revised after", day.Add(time.Hour), day.Add(time.Hour)},
+		{"rounded up", day.Add(time.Hour + time.Millisecond), day.Add(time.Hour + time.Second)},
+	}
+	for _, test := range tests {
+		if got := lastModified("2016-05-02", test.revised); !got.Equal(test.want) {
+			t.Errorf("lastModified mismatch for %s, got: %v, want: %v", test.name, got, test.want)
+		}
+	}
+	if got := lastModified("invalid", day); !got.IsZero() {
+		t.Errorf("lastModified mismatch for invalid datestamp, got: %v, want zero", got)
+	}
+}
+
+func TestNotModifiedAfterRevision(t *testing.T) {
+	day := time.Date(2016, 5, 2, 0, 0, 0, 0, time.UTC)
+	before := lastModified("2016-05-02", day.Add(time.Hour))
+	after := lastModified("2016-05-02", day.Add(time.Hour+time.Minute))
+
+	req, _ := http.NewRequest("GET", "/auditreport/", nil)
+	req.Header.Set("If-Modified-Since", before.UTC().Format(http.TimeFormat))
+	if !notModified(req, `W/"a"`, before) {
+		t.Errorf("notModified mismatch for unchanged revision, got: false, want: true")
+	}
+	if notModified(req, `W/"b"`, after) {
+		t.Errorf("notModified mismatch after a revision, got: true, want: false")
+	}
+}
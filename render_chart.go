@@ -0,0 +1,79 @@
+package render
+
+import (
+	"html/template"
+	"net/http"
+	"strings"
+
+	"appengine"
+
+	".../go/chart"
+	".../go/models"
+)
+
+// chartHandler serves the charts of the site as SVG images for exports:
+// /chart/overall.svg and /chart/{count,percent,fixcount,fixpercent}/{audit_name}.svg
+func chartHandler(w http.ResponseWriter, req *http.Request) {
+	c := appengine.NewContext(req)
+	values := strings.Split(strings.TrimSuffix(strings.TrimPrefix(req.URL.Path, "/chart/"), ".svg"), "/")
+	kind := values[0]
+	var auditName string
+	if len(values) > 1 {
+		auditName = values[1]
+	}
+
+	store, err := models.NewSqlStore(c)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer store.Close()
+	annotations, err := store.Annotations()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	annotations = models.AnnotationsByAudit(annotations, []string{auditName})[auditName]
+
+	var svg template.HTML
+	switch kind {
+	case "overall", "count", "percent":
+		stats, overall, err := store.AuditStats()
+		if err != nil {
+			c.Infof("Error on audit stats query: %s", err.Error())
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		switch {
+		case kind == "overall":
+			svg = chart.Compliance("Overall Compliance", overall.ErrCount, overall.TotalCount-overall.ErrCount).SVG()
+		case stats[auditName] == nil:
+			http.NotFound(w, req)
+			return
+		case kind == "count":
+			svg = chart.StatsCount(stats[auditName]).Annotate(annotations).SVG()
+		default:
+			svg = chart.StatsPercent(stats[auditName]).Annotate(annotations).SVG()
+		}
+	case "fixcount", "fixpercent":
+		fixStats, err := store.FixStats()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		switch {
+		case fixStats[auditName] == nil:
+			http.NotFound(w, req)
+			return
+		case kind == "fixcount":
+			svg = chart.FixCount(fixStats[auditName]).Annotate(annotations).SVG()
+		default:
+			svg = chart.FixPercent(fixStats[auditName]).Annotate(annotations).SVG()
+		}
+	default:
+		http.NotFound(w, req)
+		return
+	}
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Write([]byte(svg))
+}
@@ -0,0 +1,106 @@
+package render
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"appengine"
+	"appengine/user"
+
+	".../go/models"
+)
+
+// commentAPIHandler serves the discussion threads of findings as json. GET
+// and POST on /api/comments/?finding={key} list and add comments of the
+// finding, DELETE on /api/comments/{id} deletes a comment of the signed-in
+// user. Comments require a signed-in user, who is their author.
+func commentAPIHandler(w http.ResponseWriter, req *http.Request) {
+	c := appengine.NewContext(req)
+	var id int
+	if s := strings.TrimPrefix(req.URL.Path, "/api/comments/"); s != "" {
+		var err error
+		if id, err = strconv.Atoi(s); err != nil || id <= 0 {
+			http.Error(w, "invalid comment id "+s, http.StatusBadRequest)
+			return
+		}
+	}
+	collection := req.Method == "GET" || req.Method == "POST"
+	if collection != (id == 0) || (!collection && req.Method != "DELETE") {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	u := user.Current(c)
+	if req.Method != "GET" && u == nil {
+		http.Error(w, "sign in required", http.StatusUnauthorized)
+		return
+	}
+
+	store, err := models.NewSqlStore(c)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer store.Close()
+
+	var resp interface{}
+	switch req.Method {
+	case "GET":
+		finding := req.URL.Query().Get("finding")
+		if _, _, _, err := models.SplitFindingKey(finding); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		comments, err := store.Comments(finding)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if comments == nil {
+			comments = []*models.Comment{}
+		}
+		resp = comments
+	case "POST":
+		if !requireJSON(w, req) {
+			return
+		}
+		comment := &models.Comment{}
+		if err := json.NewDecoder(req.Body).Decode(comment); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		comment.Author = u.String()
+		if err := comment.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := store.AddComment(comment); err != nil {
+			c.Errorf("AddComment error: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		expireLatest()
+		resp = comment
+	case "DELETE":
+		if err := store.DeleteComment(id, u.String()); err == models.ErrNoComment {
+			http.NotFound(w, req)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		expireLatest()
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	js, err := json.Marshal(resp)
+	if err != nil {
+		c.Infof("Error marshal json response: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
@@ -0,0 +1,110 @@
+package render
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"appengine"
+	aemail "appengine/mail"
+
+	".../go/config"
+	".../go/digest"
+	".../go/mail"
+	".../go/models"
+)
+
+// appengineSender delivers mails through the App Engine mail API.
+type appengineSender struct {
+	c appengine.Context
+}
+
+// Send delivers m through the App Engine mail API.
+func (s *appengineSender) Send(m *mail.Message) error {
+	return aemail.Send(s.c, &aemail.Message{
+		Sender:   m.From,
+		To:       m.To,
+		Subject:  m.Subject,
+		Body:     m.Text,
+		HTMLBody: m.HTML,
+	})
+}
+
+// digestSender returns the mail sender of the digest configuration.
+func digestSender(c appengine.Context, cfg *config.DigestConfig) mail.Sender {
+	if cfg.SMTPAddr != "" {
+		return &mail.SMTPSender{Addr: cfg.SMTPAddr}
+	}
+	return &appengineSender{c}
+}
+
+// buildDigest builds the compliance digest from the SQL store.
+func buildDigest(c appengine.Context) (*digest.Digest, error) {
+	store, err := models.NewSqlStore(c)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+	return digest.Build(store, time.Now(), digest.DefaultOptions)
+}
+
+// digestHandler renders the compliance digest page of the site, or the
+// plain text digest with format=text.
+func digestHandler(w http.ResponseWriter, req *http.Request) {
+	c := appengine.NewContext(req)
+	d, err := buildDigest(c)
+	if err != nil {
+		c.Errorf("Digest error: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if req.URL.Query().Get("format") == "text" {
+		text, err := d.Text()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprint(w, text)
+		return
+	}
+	html, err := d.HTML()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := renderLayout(c, w, "digest", html); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// digestTaskHandler mails the compliance digest to the configured
+// recipients. It is run weekly by cron.
+func digestTaskHandler(w http.ResponseWriter, req *http.Request) {
+	c := appengine.NewContext(req)
+	cfg := config.Get().Digest
+	if len(cfg.To) == 0 {
+		c.Infof("No digest recipients configured")
+		fmt.Fprintln(w, "no digest recipients")
+		return
+	}
+	d, err := buildDigest(c)
+	if err != nil {
+		c.Errorf("Digest error: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	msg, err := d.Message(cfg.From, cfg.To)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := digestSender(c, &cfg).Send(msg); err != nil {
+		c.Errorf("Digest mail error: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	c.Infof("digest sent to %d recipients", len(cfg.To))
+	fmt.Fprintf(w, "digest sent to %d recipients\n", len(cfg.To))
+}
@@ -0,0 +1,129 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"appengine"
+
+	".../go/config"
+	".../go/models"
+)
+
+// buildVersion identifies the build, it is set with
+// -ldflags "-X .../go/render.buildVersion=<version>".
+var buildVersion = "dev"
+
+// defaultMaxSnapshotAge is used when no readiness threshold is configured.
+const defaultMaxSnapshotAge = 48 * time.Hour
+
+// healthStatus is the state of the site reported by the diagnostics
+// endpoints.
+type healthStatus struct {
+	BuildVersion   string              `json:"build_version"`
+	AppVersion     string              `json:"app_version"`
+	Ready          bool                `json:"ready"`
+	Errors         []string            `json:"errors,omitempty"`
+	PingLatency    string              `json:"ping_latency,omitempty"`
+	LatestSnapshot string              `json:"latest_snapshot,omitempty"`
+	SnapshotAge    string              `json:"snapshot_age,omitempty"`
+	MaxSnapshotAge string              `json:"max_snapshot_age"`
+	Store          *models.StoreStatus `json:"store,omitempty"`
+}
+
+// maxSnapshotAge returns the configured readiness threshold.
+func maxSnapshotAge() time.Duration {
+	if h := config.Get().Health.MaxSnapshotAgeHours; h > 0 {
+		return time.Duration(h) * time.Hour
+	}
+	return defaultMaxSnapshotAge
+}
+
+// checkHealth checks the store and the age of the newest snapshot.
+func checkHealth(c appengine.Context, now time.Time) *healthStatus {
+	maxAge := maxSnapshotAge()
+	status := &healthStatus{
+		BuildVersion:   buildVersion,
+		AppVersion:     appengine.VersionID(c),
+		MaxSnapshotAge: maxAge.String(),
+	}
+	fail := func(err error) *healthStatus {
+		status.Errors = append(status.Errors, err.Error())
+		return status
+	}
+
+	store, err := models.NewSqlStore(c)
+	if err != nil {
+		return fail(err)
+	}
+	defer store.Close()
+
+	start := time.Now()
+	if err := store.Ping(); err != nil {
+		return fail(err)
+	}
+	status.PingLatency = time.Since(start).String()
+	status.Store = store.Status()
+
+	latest, err := store.LatestSnapshot()
+	if err != nil {
+		return fail(err)
+	}
+	if latest == "" {
+		return fail(fmt.Errorf("no snapshots"))
+	}
+	t, err := models.ParseDatestamp(latest)
+	if err != nil {
+		return fail(err)
+	}
+	age := now.Sub(t)
+	status.LatestSnapshot = latest
+	status.SnapshotAge = age.String()
+	if age > maxAge {
+		return fail(fmt.Errorf("snapshot %s is older than %s", latest, maxAge))
+	}
+	status.Ready = true
+	return status
+}
+
+// healthzHandler reports that the process serves requests.
+func healthzHandler(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "ok")
+}
+
+// readyzHandler reports whether the store is reachable and the newest
+// snapshot is recent enough.
+func readyzHandler(w http.ResponseWriter, req *http.Request) {
+	c := appengine.NewContext(req)
+	status := checkHealth(c, time.Now())
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if !status.Ready {
+		c.Warningf("Not ready: %v", status.Errors)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		for _, e := range status.Errors {
+			fmt.Fprintln(w, e)
+		}
+		return
+	}
+	fmt.Fprintf(w, "ok %s\n", status.LatestSnapshot)
+}
+
+// debugStatusHandler returns the diagnostics of the site as json.
+func debugStatusHandler(w http.ResponseWriter, req *http.Request) {
+	c := appengine.NewContext(req)
+	status := checkHealth(c, time.Now())
+	js, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		c.Infof("Error marshal json response: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if !status.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	w.Write(js)
+}
@@ -0,0 +1,106 @@
+package render
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"appengine"
+
+	".../go/config"
+	".../go/models"
+)
+
+// maxBatchBytes bounds the size of an ingestion request body.
+const maxBatchBytes = 32 << 20
+
+// producer returns the configured producer authenticated by the bearer token
+// of the request, or nil.
+func producer(req *http.Request, cfg *config.IngestConfig) *config.ProducerConfig {
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if token == "" || token == req.Header.Get("Authorization") {
+		return nil
+	}
+	sum := sha256.Sum256([]byte(token))
+	hash := []byte(hex.EncodeToString(sum[:]))
+	for _, p := range cfg.Producers {
+		if subtle.ConstantTimeCompare(hash, []byte(strings.ToLower(p.TokenSHA256))) == 1 {
+			return p
+		}
+	}
+	return nil
+}
+
+// ingestHandler accepts a json batch of findings and stats of a snapshot
+// from an audit producer. The snapshot is committed to the audit tables by
+// the batch marked complete.
+func ingestHandler(w http.ResponseWriter, req *http.Request) {
+	c := appengine.NewContext(req)
+	if req.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	p := producer(req, &config.Get().Ingest)
+	if p == nil {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "invalid or missing bearer token", http.StatusUnauthorized)
+		return
+	}
+
+	b := &models.Batch{}
+	d := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxBatchBytes))
+	d.DisallowUnknownFields()
+	if err := d.Decode(b); err != nil {
+		http.Error(w, "invalid batch: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	b.Producer = p.Name
+	if err := b.Validate(); err != nil {
+		http.Error(w, "invalid batch: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	allowed := make(map[string]bool)
+	for _, auditName := range p.AuditNames {
+		allowed[auditName] = true
+	}
+	for auditName := range b.AuditNames() {
+		if !allowed[auditName] {
+			http.Error(w, fmt.Sprintf("producer %s may not deliver audit %s", p.Name, auditName), http.StatusForbidden)
+			return
+		}
+	}
+
+	store, err := models.NewSqlStore(c)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer store.Close()
+	res, err := store.Ingest(b)
+	if err == models.ErrBatchConflict {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		c.Errorf("Ingest error of batch %s from %s: %v", b.ID, p.Name, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	c.Infof("batch %s from %s for %s: findings %d, stats %d, duplicate %t, committed %t",
+		b.ID, p.Name, b.Datestamp, res.Findings, res.Stats, res.Duplicate, res.Committed)
+	if res.Committed {
+		expireLatest()
+	}
+
+	js, err := json.Marshal(res)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
@@ -0,0 +1,42 @@
+package render
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"testing"
+
+	".../go/config"
+)
+
+func TestProducer(t *testing.T) {
+	sum := sha256.Sum256([]byte("secret"))
+	cfg := &config.IngestConfig{Producers: []*config.ProducerConfig{
+		{Name: "other", TokenSHA256: strings.Repeat("0", 64)},
+		{Name: "scanner", TokenSHA256: strings.ToUpper(hex.EncodeToString(sum[:]))},
+	}}
+	tests := []struct {
+		auth, want string
+	}{
+		{"Bearer secret", "scanner"},
+		{"Bearer wrong", ""},
+		{"Bearer ", ""},
+		{"secret", ""},
+		{"Basic secret", ""},
+		{"", ""},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest("POST", "/api/ingest", nil)
+		if test.auth != "" {
+			req.Header.Set("Authorization", test.auth)
+		}
+		got := ""
+		if p := producer(req, cfg); p != nil {
+			got = p.Name
+		}
+		if got != test.want {
+			t.Errorf("producer mismatch for %q, got: %q, want: %q", test.auth, got, test.want)
+		}
+	}
+}
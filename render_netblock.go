@@ -0,0 +1,120 @@
+package render
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"appengine"
+
+	".../go/link"
+	".../go/models"
+)
+
+// netblockHistory contains the history of a netblock across all audits and
+// snapshots. Truncated is set if the oldest records were left out, so that
+// the timeline starts later than the history.
+type netblockHistory struct {
+	Prefix    string                `json:"prefix"`
+	Records   []*models.AuditRecord `json:"records"`
+	Timeline  []*models.FindingSpan `json:"timeline"`
+	Truncated bool                  `json:"truncated"`
+}
+
+// linkedRecord is an audit record with the external links of its fields.
+type linkedRecord struct {
+	*models.AuditRecord
+	Links []*link.Link `json:"links"`
+}
+
+// linkedSpan is a finding span with the external links of its fields.
+type linkedSpan struct {
+	*models.FindingSpan
+	Links []*link.Link `json:"links"`
+}
+
+// linkedHistory is the json form of a netblock history.
+type linkedHistory struct {
+	Prefix    string          `json:"prefix"`
+	Records   []*linkedRecord `json:"records"`
+	Timeline  []*linkedSpan   `json:"timeline"`
+	Truncated bool            `json:"truncated"`
+}
+
+// withLinks adds the external links to the records and spans of history.
+func withLinks(history *netblockHistory) *linkedHistory {
+	lh := &linkedHistory{Prefix: history.Prefix, Truncated: history.Truncated}
+	for _, ar := range history.Records {
+		lh.Records = append(lh.Records, &linkedRecord{ar, links.Record(ar)})
+	}
+	for _, span := range history.Timeline {
+		lh.Timeline = append(lh.Timeline, &linkedSpan{span, links.Span(span)})
+	}
+	return lh
+}
+
+// loadNetblockHistory queries the history of the netblock prefix in the URL
+// path following urlPrefix.
+func loadNetblockHistory(c appengine.Context, req *http.Request, urlPrefix string) (*netblockHistory, int, error) {
+	prefix := strings.TrimPrefix(req.URL.Path, urlPrefix)
+	if _, err := models.ParseHistoryPrefix(prefix); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	store, err := models.NewSqlStore(c)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	defer store.Close()
+
+	snapshots, _, _, err := store.Snapshots()
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	records, truncated, err := store.NetblockHistory(prefix)
+	if err != nil {
+		c.Errorf("NetblockHistory error: %v", err)
+		return nil, http.StatusInternalServerError, err
+	}
+	c.Infof("netblock %s records len: %d, truncated: %t", prefix, len(records), truncated)
+
+	return &netblockHistory{
+		Prefix:    prefix,
+		Records:   records,
+		Timeline:  models.BuildTimeline(records, snapshots),
+		Truncated: truncated,
+	}, http.StatusOK, nil
+}
+
+// netblockHandler renders the netblock history page of the site.
+func netblockHandler(w http.ResponseWriter, req *http.Request) {
+	c := appengine.NewContext(req)
+	history, code, err := loadNetblockHistory(c, req, "/netblock/")
+	if err != nil {
+		http.Error(w, err.Error(), code)
+		return
+	}
+	if err := renderLayout(c, w, "netblock", history); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// netblockAPIHandler returns the netblock history with external links as
+// json.
+func netblockAPIHandler(w http.ResponseWriter, req *http.Request) {
+	c := appengine.NewContext(req)
+	history, code, err := loadNetblockHistory(c, req, "/api/netblock/")
+	if err != nil {
+		http.Error(w, err.Error(), code)
+		return
+	}
+	js, err := json.Marshal(withLinks(history))
+	if err != nil {
+		c.Infof("Error marshal json response: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
@@ -0,0 +1,30 @@
+package render
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	".../go/models"
+)
+
+func TestWithLinksTruncated(t *testing.T) {
+	for _, truncated := range []bool{false, true} {
+		history := &netblockHistory{
+			Prefix:    "10.1.0.0/16",
+			Records:   []*models.AuditRecord{{Netblock: "10.1.2.0/24", AuditName: "vlan", AuditCode: "E1"}},
+			Truncated: truncated,
+		}
+		js, err := json.Marshal(withLinks(history))
+		if err != nil {
+			t.Fatalf("json.Marshal error: %v", err)
+		}
+		want := `"truncated":false`
+		if truncated {
+			want = `"truncated":true`
+		}
+		if !strings.Contains(string(js), want) {
+			t.Errorf("withLinks json mismatch for truncated %t, got: %s, want: %s", truncated, js, want)
+		}
+	}
+}
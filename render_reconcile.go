@@ -0,0 +1,110 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"appengine"
+
+	".../go/config"
+	".../go/mail"
+	".../go/models"
+)
+
+// loadReconciliation checks the whitelist tickets of the findings of the
+// newest snapshot against the ticket table.
+func loadReconciliation(c appengine.Context) (*models.Reconciliation, error) {
+	store, err := models.NewSqlStore(c)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+
+	snapshot, err := store.LatestSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	records, err := store.SnapshotRecords(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	tickets, err := store.AuditTickets()
+	if err != nil {
+		return nil, err
+	}
+	c.Infof("reconciling %d records against %d tickets", len(records), len(tickets))
+	return models.Reconcile(snapshot, records, tickets), nil
+}
+
+// reconcileHandler renders the ticket reconciliation page of the site. It is
+// not cacheable, ticket states change without a new snapshot.
+func reconcileHandler(w http.ResponseWriter, req *http.Request) {
+	c := appengine.NewContext(req)
+	rc, err := loadReconciliation(c)
+	if err != nil {
+		c.Errorf("Reconciliation error: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := renderLayout(c, w, "reconcile", rc); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// reconcileText returns the plain text report of a reconciliation.
+func reconcileText(rc *models.Reconciliation) string {
+	b := bytes.NewBuffer([]byte{})
+	fmt.Fprintf(b, "Ticket reconciliation of snapshot %s\n", rc.Snapshot)
+	fmt.Fprintf(b, "\nFindings whitelisted by closed tickets, to be reopened (%d):\n", len(rc.Reopen))
+	for _, ar := range rc.Reopen {
+		fmt.Fprintf(b, "  %s %s %s\n", ar.Netblock, ar.AuditName, ar.AuditCode)
+	}
+	fmt.Fprintf(b, "\nFindings referencing missing tickets (%d):\n", len(rc.Missing))
+	for _, ref := range rc.Missing {
+		fmt.Fprintf(b, "  %s %s %s: %s\n", ref.Finding.Netblock, ref.Finding.AuditName, ref.Finding.AuditCode, ref.Ref)
+	}
+	fmt.Fprintf(b, "\nFindings referencing closed tickets (%d):\n", len(rc.Closed))
+	for _, ref := range rc.Closed {
+		fmt.Fprintf(b, "  %s %s %s: %s (%s)\n", ref.Finding.Netblock, ref.Finding.AuditName, ref.Finding.AuditCode, ref.Ref, ref.Ticket.State)
+	}
+	fmt.Fprintf(b, "\nOpen tickets without findings (%d):\n", len(rc.Orphaned))
+	for _, t := range rc.Orphaned {
+		fmt.Fprintf(b, "  b/%d %s: %s\n", t.TicketID, t.AuditName, t.Summary)
+	}
+	return b.String()
+}
+
+// reconcileTaskHandler reconciles the whitelist tickets and mails the report
+// to the configured recipients when there are issues. It is run daily by
+// cron.
+func reconcileTaskHandler(w http.ResponseWriter, req *http.Request) {
+	c := appengine.NewContext(req)
+	rc, err := loadReconciliation(c)
+	if err != nil {
+		c.Errorf("Reconciliation error: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	c.Infof("reconciliation: missing %d, closed %d, reopen %d, orphaned %d",
+		len(rc.Missing), len(rc.Closed), len(rc.Reopen), len(rc.Orphaned))
+
+	cfg := config.Get()
+	if rc.Issues() == 0 || len(cfg.Tickets.ReconcileTo) == 0 {
+		fmt.Fprintf(w, "reconciliation issues: %d, not mailed\n", rc.Issues())
+		return
+	}
+	msg := &mail.Message{
+		From:    cfg.Digest.From,
+		To:      cfg.Tickets.ReconcileTo,
+		Subject: "[Dragonwell] Ticket reconciliation " + rc.Snapshot,
+		Text:    reconcileText(rc),
+	}
+	if err := digestSender(c, &cfg.Digest).Send(msg); err != nil {
+		c.Errorf("Reconciliation mail error: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprintf(w, "reconciliation issues: %d, mailed to %d recipients\n", rc.Issues(), len(cfg.Tickets.ReconcileTo))
+}
@@ -0,0 +1,227 @@
+package render
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+
+	".../go/chart"
+	".../go/config"
+	".../go/link"
+	".../go/models"
+	".../go/slo"
+	".../go/static"
+)
+
+// templateFS holds the HTML templates compiled into the binary.
+//
+//go:embed template/*.html
+var templateFS embed.FS
+
+// embedDir is the directory of the templates within templateFS.
+const embedDir = "template"
+
+// layoutFile is the base template every page is rendered in.
+const layoutFile = "_main_layout.html"
+
+// devTemplateEnv names the environment variable which enables the template
+// dev mode. When set to a directory, templates are re-parsed from it on every
+// request so changes show up without a redeploy.
+const devTemplateEnv = "DW_TEMPLATE_DIR"
+
+// pageData maps every page to a value of the data it is rendered with. The
+// values are used to check the templates at startup.
+var pageData = map[string]interface{}{
+	"auditreport": reportData{},
+	"auditchart":  chartData{},
+	"fixchart":    fixChartData{},
+	"auditticket": &ticketData{},
+	"netblock":    &netblockHistory{},
+	"reconcile":   &models.Reconciliation{},
+	"annotations": &annotationsData{},
+	"digest":      template.HTML(""),
+}
+
+// links builds the external links of field values.
+var links *link.Registry
+
+// mustLoadLinks returns the link registry of the configured URL templates.
+// This function will panic if a template is invalid.
+func mustLoadLinks() *link.Registry {
+	r, err := link.New(config.Get().Links)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// linkHTML returns an anchor to the external link of the field value with the
+// value as text, or the value if there is no link.
+func linkHTML(field string, value interface{}) template.HTML {
+	v := fmt.Sprint(value)
+	u := links.URL(field, v)
+	if u == "" {
+		return template.HTML(template.HTMLEscapeString(v))
+	}
+	return template.HTML(fmt.Sprintf(`<a href="%s" class=column_link target=_ipdb>%s</a>`,
+		template.HTMLEscapeString(u), template.HTMLEscapeString(v)))
+}
+
+// templateFuncs are the functions available to page templates.
+var templateFuncs = template.FuncMap{
+	"asset": static.Path,
+	"link":  linkHTML,
+	"linkURL": func(field string, value interface{}) string {
+		return links.URL(field, fmt.Sprint(value))
+	},
+	"statsCountChart": func(records []*models.StatsRecord, annotations []*models.Annotation) template.HTML {
+		return chart.StatsCount(records).Annotate(annotations).SVG()
+	},
+	"statsPercentChart": func(records []*models.StatsRecord, annotations []*models.Annotation) template.HTML {
+		return chart.StatsPercent(records).Annotate(annotations).SVG()
+	},
+	"fixCountChart": func(records []*models.FixStatsRecord, annotations []*models.Annotation) template.HTML {
+		return chart.FixCount(records).Annotate(annotations).SVG()
+	},
+	"fixPercentChart": func(records []*models.FixStatsRecord, annotations []*models.Annotation) template.HTML {
+		return chart.FixPercent(records).Annotate(annotations).SVG()
+	},
+	"complianceChart": func(title string, errCount, compliant int) template.HTML {
+		return chart.Compliance(title, errCount, compliant).SVG()
+	},
+	"burndownChart": func(s *slo.Status) template.HTML {
+		return chart.Burndown(s).SVG()
+	},
+	"ticketTrendChart": func(records []*models.TicketTrendRecord) template.HTML {
+		return chart.TicketTrend(records).SVG()
+	},
+}
+
+// templates holds the parsed page templates keyed on page name.
+var templates map[string]*template.Template
+
+// parsePage returns the template of the page in fsys with the layout as the
+// base template.
+func parsePage(fsys fs.FS, name string) (*template.Template, error) {
+	return template.New(layoutFile).Funcs(templateFuncs).ParseFS(fsys, layoutFile, name+".html")
+}
+
+// loadTemplates parses and validates the templates of all pages in fsys.
+func loadTemplates(fsys fs.FS) (map[string]*template.Template, error) {
+	var errs []string
+	ts := make(map[string]*template.Template)
+	for name, data := range pageData {
+		t, err := parsePage(fsys, name)
+		if err == nil {
+			err = validateTemplate(t, data)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		ts[name] = t
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid templates: %s", strings.Join(errs, "; "))
+	}
+	return ts, nil
+}
+
+// mustLoadTemplates returns the validated templates compiled into the binary.
+// This function will panic if a template cannot be parsed or executed.
+func mustLoadTemplates() map[string]*template.Template {
+	fsys, err := fs.Sub(templateFS, embedDir)
+	if err != nil {
+		panic(err)
+	}
+	ts, err := loadTemplates(fsys)
+	if err != nil {
+		panic(err)
+	}
+	return ts
+}
+
+// pageTemplate returns the template of the named page. In dev mode it is
+// parsed from disk.
+func pageTemplate(name string) (*template.Template, error) {
+	if dir := os.Getenv(devTemplateEnv); dir != "" {
+		t, err := parsePage(os.DirFS(dir), name)
+		if err != nil {
+			return nil, err
+		}
+		return t, validateTemplate(t, pageData[name])
+	}
+	t, ok := templates[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown template %q", name)
+	}
+	return t, nil
+}
+
+// validateTemplate executes the layout of t with sample data of the type of
+// data, so that missing fields and functions are found before a page is
+// served.
+func validateTemplate(t *template.Template, data interface{}) error {
+	if data == nil {
+		return nil
+	}
+	headerInfo := struct {
+		UserName    string
+		ContentData interface{}
+	}{
+		UserName:    "validate",
+		ContentData: sampleValue(reflect.TypeOf(data), 0).Interface(),
+	}
+	return t.ExecuteTemplate(ioutil.Discard, "layout", headerInfo)
+}
+
+// maxSampleDepth bounds the nesting of sample values.
+const maxSampleDepth = 6
+
+// sampleValue returns a value of type t in which pointers are set, and
+// slices and maps hold one element, so that template range and with blocks
+// are executed.
+func sampleValue(t reflect.Type, depth int) reflect.Value {
+	v := reflect.New(t).Elem()
+	if depth > maxSampleDepth {
+		return v
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		v.Set(sampleValue(t.Elem(), depth+1).Addr())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if f := t.Field(i); f.PkgPath == "" {
+				v.Field(i).Set(sampleValue(f.Type, depth+1))
+			}
+		}
+	case reflect.Slice:
+		v.Set(reflect.Append(reflect.MakeSlice(t, 0, 1), sampleValue(t.Elem(), depth+1)))
+	case reflect.Map:
+		v.Set(reflect.MakeMap(t))
+		v.SetMapIndex(sampleValue(t.Key(), depth+1), sampleValue(t.Elem(), depth+1))
+	case reflect.String:
+		v.SetString("sample")
+	}
+	return v
+}
+
+// executePage renders the named page with data into a buffer, so that
+// errors do not leave a partial page behind.
+func executePage(name string, data interface{}) (*bytes.Buffer, error) {
+	t, err := pageTemplate(name)
+	if err != nil {
+		return nil, err
+	}
+	b := bytes.NewBuffer([]byte{})
+	if err := t.ExecuteTemplate(b, "layout", data); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
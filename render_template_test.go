@@ -0,0 +1,24 @@
+package render
+
+import (
+	"io/fs"
+	"testing"
+)
+
+// TestLoadTemplates loads the templates compiled into the binary against the
+// embedded assets, which fails for an asset missing from static/assets.
+func TestLoadTemplates(t *testing.T) {
+	fsys, err := fs.Sub(templateFS, embedDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts, err := loadTemplates(fsys)
+	if err != nil {
+		t.Fatalf("loadTemplates error: %v", err)
+	}
+	for name := range pageData {
+		if ts[name] == nil {
+			t.Errorf("loadTemplates lacks page %s", name)
+		}
+	}
+}
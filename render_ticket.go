@@ -0,0 +1,104 @@
+package render
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	".../go/config"
+	".../go/models"
+)
+
+// agingBuckets are the upper bounds in days of the open ticket age buckets,
+// the last bucket is unbounded.
+var agingBuckets = []struct {
+	label   string
+	maxDays int
+}{
+	{"0-7 days", 7},
+	{"8-30 days", 30},
+	{"31-90 days", 90},
+	{"> 90 days", -1},
+}
+
+// ticketRow is a ticket with its age and SLA.
+type ticketRow struct {
+	*models.TicketRecord
+	Closed   bool
+	AgeDays  int
+	SLADays  int
+	Breached bool
+}
+
+// agingBucket is the number of open tickets within an age range.
+type agingBucket struct {
+	Label string
+	Count int
+}
+
+// ticketData is the data of the AuditTicket page.
+type ticketData struct {
+	Tickets    []*ticketRow
+	Filter     models.TicketFilter
+	AuditNames []string
+	States     []string
+	Buckets    []*agingBucket
+	Open       int
+	Breaches   int
+	Trend      []*models.TicketTrendRecord
+}
+
+// ticketFilter returns the ticket filter of the request query.
+func ticketFilter(req *http.Request) models.TicketFilter {
+	q := req.URL.Query()
+	return models.TicketFilter{
+		AuditName: q.Get("auditname"),
+		AuditCode: q.Get("auditcode"),
+		State:     q.Get("state"),
+		From:      q.Get("from"),
+		To:        q.Get("to"),
+	}
+}
+
+// newTicketData computes the age, SLA and aging buckets of tickets as of
+// now, and the audit names and states offered by the filter.
+func newTicketData(tickets []*models.TicketRecord, filter models.TicketFilter, trend []*models.TicketTrendRecord,
+	cfg *config.TicketsConfig, now time.Time) *ticketData {
+	d := &ticketData{Filter: filter, Trend: trend}
+	for _, b := range agingBuckets {
+		d.Buckets = append(d.Buckets, &agingBucket{Label: b.label})
+	}
+	auditNames := make(map[string]bool)
+	states := map[string]bool{models.TicketOpen: true, models.TicketClosed: true}
+	for _, t := range tickets {
+		auditNames[t.AuditName] = true
+		states[t.State] = true
+		row := &ticketRow{TicketRecord: t, Closed: t.IsClosed(), SLADays: cfg.SLA(t.AuditName)}
+		if age, err := t.Age(now); err == nil {
+			row.AgeDays = int(age.Hours() / 24)
+		}
+		if !row.Closed {
+			d.Open++
+			row.Breached = row.SLADays > 0 && row.AgeDays > row.SLADays
+			if row.Breached {
+				d.Breaches++
+			}
+			for i, b := range agingBuckets {
+				if b.maxDays < 0 || row.AgeDays <= b.maxDays {
+					d.Buckets[i].Count++
+					break
+				}
+			}
+		}
+		d.Tickets = append(d.Tickets, row)
+	}
+	for k := range auditNames {
+		d.AuditNames = append(d.AuditNames, k)
+	}
+	sort.Strings(d.AuditNames)
+	for k := range states {
+		d.States = append(d.States, k)
+	}
+	sort.Strings(d.States)
+	return d
+}
@@ -0,0 +1,5 @@
+-- Alerts which have been notified and did not clear yet, see dw_alert.go.
+CREATE TABLE IF NOT EXISTS ipdb_alert_state (
+  alert_key VARCHAR(255) NOT NULL,
+  PRIMARY KEY (alert_key)
+);
@@ -0,0 +1,10 @@
+-- Chart annotations, see dw_annotation.go.
+CREATE TABLE IF NOT EXISTS ipdb_annotation (
+  id INT NOT NULL AUTO_INCREMENT,
+  datestamp DATE NOT NULL,
+  audit_name VARCHAR(64) NOT NULL,
+  title VARCHAR(255) NOT NULL,
+  link VARCHAR(2048) NOT NULL DEFAULT '',
+  PRIMARY KEY (id),
+  KEY (datestamp)
+);
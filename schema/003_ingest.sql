@@ -0,0 +1,48 @@
+-- Ingested batches and the findings and stats staged by audit producers until
+-- their snapshot is committed, see dw_ingest.go. The staging tables have the
+-- columns of ipdb_audit and ipdb_audit_stats and the producer.
+CREATE TABLE IF NOT EXISTS ipdb_ingest_batch (
+  batch_id VARCHAR(64) NOT NULL,
+  producer VARCHAR(64) NOT NULL,
+  datestamp DATE NOT NULL,
+  complete BOOL NOT NULL,
+  PRIMARY KEY (batch_id)
+);
+
+CREATE TABLE IF NOT EXISTS ipdb_audit_stage (
+  producer VARCHAR(64) NOT NULL,
+  netblock VARCHAR(64) NOT NULL,
+  tags TEXT NOT NULL,
+  vlan_id VARCHAR(64) NOT NULL,
+  building VARCHAR(64) NOT NULL,
+  gateway VARCHAR(255) NOT NULL,
+  attributes TEXT NOT NULL,
+  child_attributes TEXT NOT NULL,
+  expected_value TEXT NOT NULL,
+  network VARCHAR(255) NOT NULL,
+  audit_name VARCHAR(64) NOT NULL,
+  audit_code VARCHAR(64) NOT NULL,
+  correlates TEXT NOT NULL,
+  audit_msg TEXT NOT NULL,
+  severity VARCHAR(16) NOT NULL,
+  state VARCHAR(16) NOT NULL,
+  fix_state VARCHAR(16) NOT NULL,
+  fix_msg TEXT NOT NULL,
+  tickets TEXT NOT NULL,
+  datestamp DATE NOT NULL,
+  KEY (datestamp, producer)
+);
+
+CREATE TABLE IF NOT EXISTS ipdb_audit_stats_stage (
+  producer VARCHAR(64) NOT NULL,
+  audit_name VARCHAR(64) NOT NULL,
+  err_count INT NOT NULL,
+  warn_count INT NOT NULL,
+  err_per DECIMAL(5,4) NOT NULL,
+  warn_per DECIMAL(5,4) NOT NULL,
+  total INT NOT NULL,
+  autofix_count INT NULL,
+  fixed_count INT NULL,
+  datestamp DATE NOT NULL,
+  KEY (datestamp, producer)
+);
@@ -0,0 +1,12 @@
+-- Discussion threads of findings, see dw_comment.go.
+CREATE TABLE IF NOT EXISTS ipdb_comment (
+  id INT NOT NULL AUTO_INCREMENT,
+  netblock VARCHAR(64) NOT NULL,
+  audit_name VARCHAR(64) NOT NULL,
+  audit_code VARCHAR(64) NOT NULL,
+  author VARCHAR(255) NOT NULL,
+  body TEXT NOT NULL,
+  created DATETIME NOT NULL,
+  PRIMARY KEY (id),
+  KEY (audit_name, netblock, audit_code)
+);
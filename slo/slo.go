@@ -0,0 +1,183 @@
+// Package slo tracks the compliance objectives of audits and projects when
+// they are reached from the audit stats history.
+package slo
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	".../go/config"
+	".../go/models"
+)
+
+// projectionWindow is the span of the most recent stats the trend towards an
+// objective is fitted on.
+const projectionWindow = 30 * 24 * time.Hour
+
+// maxProjectionDays bounds projections, trends too flat to reach an
+// objective within it are not projected.
+const maxProjectionDays = 10 * 365
+
+// State is the progress of an objective.
+type State string
+
+const (
+	// Met objectives are at or below their target.
+	Met State = "met"
+	// OnTrack objectives are projected to be met by their target date.
+	OnTrack State = "on track"
+	// AtRisk objectives are not projected to be met by their target date.
+	AtRisk State = "at risk"
+	// Missed objectives were not met by their target date.
+	Missed State = "missed"
+	// NoData objectives have no stats of their audit.
+	NoData State = "no data"
+)
+
+// Objective is the error percentage an audit is to be at or below by a
+// target date.
+type Objective struct {
+	AuditName     string
+	MaxErrPercent float64
+	Target        time.Time
+}
+
+// Point is the error percentage of a snapshot.
+type Point struct {
+	Time       time.Time
+	ErrPercent float64
+}
+
+// Status is the progress of an objective as of a snapshot.
+type Status struct {
+	*Objective
+	State State
+	// Current is the error percentage of the newest snapshot.
+	Current float64
+	// History holds the error percentage of every snapshot, oldest first.
+	History []Point
+	// Projected is the date the objective is projected to be met on, it is
+	// zero if the error percentage does not decrease.
+	Projected time.Time
+}
+
+// Behind reports whether the objective is at risk or missed.
+func (s *Status) Behind() bool {
+	return s.State == AtRisk || s.State == Missed
+}
+
+// ObjectivesFromConfig validates the configured objectives.
+func ObjectivesFromConfig(cfgObjectives []*config.ObjectiveConfig) ([]*Objective, error) {
+	var objectives []*Objective
+	for _, o := range cfgObjectives {
+		if o.AuditName == "" {
+			return nil, fmt.Errorf("objective without audit name")
+		}
+		target, err := models.ParseDatestamp(o.TargetDate)
+		if err != nil {
+			return nil, fmt.Errorf("objective of %s: invalid target date %q", o.AuditName, o.TargetDate)
+		}
+		if o.MaxErrPercent < 0 || o.MaxErrPercent > 100 {
+			return nil, fmt.Errorf("objective of %s: max_err_percent %g out of range", o.AuditName, o.MaxErrPercent)
+		}
+		objectives = append(objectives, &Objective{o.AuditName, o.MaxErrPercent, target})
+	}
+	return objectives, nil
+}
+
+// Evaluate computes the status of every objective from the audit stats as of
+// now.
+func Evaluate(objectives []*Objective, stats map[string][]*models.StatsRecord, now time.Time) []*Status {
+	var statuses []*Status
+	for _, o := range objectives {
+		s := &Status{Objective: o, History: history(stats[o.AuditName])}
+		statuses = append(statuses, s)
+		if len(s.History) == 0 {
+			s.State = NoData
+			continue
+		}
+		s.Current = s.History[len(s.History)-1].ErrPercent
+		s.Projected = project(s.History, o.MaxErrPercent)
+		switch {
+		case s.Current <= o.MaxErrPercent:
+			s.State = Met
+		case now.After(o.Target):
+			s.State = Missed
+		case !s.Projected.IsZero() && !s.Projected.After(o.Target):
+			s.State = OnTrack
+		default:
+			s.State = AtRisk
+		}
+	}
+	return statuses
+}
+
+// history returns the error percentages of the records ordered by time.
+func history(records []*models.StatsRecord) []Point {
+	var points []Point
+	for _, r := range records {
+		t, err := models.ParseDatestamp(r.Datestamp)
+		if err != nil {
+			continue
+		}
+		v, err := strconv.ParseFloat(r.ErrPer, 64)
+		if err != nil {
+			continue
+		}
+		points = append(points, Point{t, v * 100})
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+	return points
+}
+
+// project fits a line through the points within projectionWindow of the
+// newest one, and returns the date the line reaches target. It returns the
+// zero time if the points do not decrease towards target.
+func project(points []Point, target float64) time.Time {
+	latest := points[len(points)-1]
+	if latest.ErrPercent <= target {
+		return latest.Time
+	}
+	var window []Point
+	for _, p := range points {
+		if latest.Time.Sub(p.Time) <= projectionWindow {
+			window = append(window, p)
+		}
+	}
+	if len(window) < 2 {
+		return time.Time{}
+	}
+
+	// Least squares fit of the error percentage over days since the start
+	// of the window.
+	start := window[0].Time
+	var n, sx, sy, sxx, sxy float64
+	for _, p := range window {
+		x := p.Time.Sub(start).Hours() / 24
+		n++
+		sx += x
+		sy += p.ErrPercent
+		sxx += x * x
+		sxy += x * p.ErrPercent
+	}
+	d := n*sxx - sx*sx
+	if d == 0 {
+		return time.Time{}
+	}
+	slope := (n*sxy - sx*sy) / d
+	if slope >= 0 {
+		return time.Time{}
+	}
+	intercept := (sy - slope*sx) / n
+	days := (target - intercept) / slope
+	if days > maxProjectionDays {
+		return time.Time{}
+	}
+	projected := start.Add(time.Duration(days * 24 * float64(time.Hour)))
+	if projected.Before(latest.Time) {
+		return latest.Time
+	}
+	return projected
+}
@@ -0,0 +1,81 @@
+package slo
+
+import (
+	"testing"
+	"time"
+
+	".../go/config"
+	".../go/models"
+)
+
+func date(s string) time.Time {
+	t, err := models.ParseDatestamp(s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestObjectivesFromConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *config.ObjectiveConfig
+		wantErr bool
+	}{
+		{name: "valid", cfg: &config.ObjectiveConfig{AuditName: "dns", MaxErrPercent: 1, TargetDate: "2016-12-31"}},
+		{name: "no audit", cfg: &config.ObjectiveConfig{MaxErrPercent: 1, TargetDate: "2016-12-31"}, wantErr: true},
+		{name: "bad date", cfg: &config.ObjectiveConfig{AuditName: "dns", MaxErrPercent: 1, TargetDate: "end of year"}, wantErr: true},
+		{name: "bad percent", cfg: &config.ObjectiveConfig{AuditName: "dns", MaxErrPercent: 120, TargetDate: "2016-12-31"}, wantErr: true},
+	}
+	for _, test := range tests {
+		_, err := ObjectivesFromConfig([]*config.ObjectiveConfig{test.cfg})
+		if (err != nil) != test.wantErr {
+			t.Errorf("Error mismatch for test: %s, got: %v, want error: %t", test.name, err, test.wantErr)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	stats := map[string][]*models.StatsRecord{
+		// Decreasing by 0.1% a day, reaches 1% on 2016-05-31.
+		"dns": {
+			{AuditName: "dns", ErrPer: "0.0300", Datestamp: "2016-05-11"},
+			{AuditName: "dns", ErrPer: "0.0400", Datestamp: "2016-05-01"},
+			{AuditName: "dns", ErrPer: "0.0350", Datestamp: "2016-05-06"},
+		},
+		"vlan": {
+			{AuditName: "vlan", ErrPer: "0.0200", Datestamp: "2016-05-01"},
+			{AuditName: "vlan", ErrPer: "0.0250", Datestamp: "2016-05-11"},
+		},
+		"gateway": {
+			{AuditName: "gateway", ErrPer: "0.0050", Datestamp: "2016-05-11"},
+		},
+	}
+	now := date("2016-05-12")
+	tests := []struct {
+		name          string
+		objective     *Objective
+		wantState     State
+		wantProjected string
+	}{
+		{name: "on track", objective: &Objective{"dns", 1, date("2016-06-30")}, wantState: OnTrack, wantProjected: "2016-05-31"},
+		{name: "at risk", objective: &Objective{"dns", 1, date("2016-05-20")}, wantState: AtRisk, wantProjected: "2016-05-31"},
+		{name: "increasing", objective: &Objective{"vlan", 1, date("2016-06-30")}, wantState: AtRisk},
+		{name: "missed", objective: &Objective{"vlan", 1, date("2016-05-01")}, wantState: Missed},
+		{name: "met", objective: &Objective{"gateway", 1, date("2016-06-30")}, wantState: Met, wantProjected: "2016-05-11"},
+		{name: "no data", objective: &Objective{"dhcp", 1, date("2016-06-30")}, wantState: NoData},
+	}
+	for _, test := range tests {
+		s := Evaluate([]*Objective{test.objective}, stats, now)[0]
+		if s.State != test.wantState {
+			t.Errorf("State mismatch for test: %s, got: %s, want: %s", test.name, s.State, test.wantState)
+		}
+		var projected string
+		if !s.Projected.IsZero() {
+			projected = s.Projected.Format(models.DateFormat)
+		}
+		if projected != test.wantProjected {
+			t.Errorf("Projected date mismatch for test: %s, got: %q, want: %q", test.name, projected, test.wantProjected)
+		}
+	}
+}
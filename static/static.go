@@ -0,0 +1,157 @@
+// Package static serves the front-end assets of the site from the binary, so
+// that pages do not load anything from external hosts.
+package static
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// assetFS holds the versioned front-end assets. Vendored libraries carry
+// their version in the file name, they are fetched by vendor.sh.
+//
+//go:embed assets/*
+var assetFS embed.FS
+
+// URLPrefix is the path assets are served under.
+const URLPrefix = "/static/"
+
+// maxAge is the cache lifetime of fingerprinted assets.
+const maxAge = 365 * 24 * time.Hour
+
+// plainMaxAge is the cache lifetime of assets served under their plain path.
+const plainMaxAge = 24 * time.Hour
+
+// asset is an embedded file and its URL.
+type asset struct {
+	name        string
+	contentType string
+	content     []byte
+	url         string
+	// fingerprinted is set if the URL changes with the content.
+	fingerprinted bool
+}
+
+var (
+	// assets maps asset file names to assets.
+	assets = make(map[string]*asset)
+	// byURL maps fingerprinted URLs to assets.
+	byURL = make(map[string]*asset)
+)
+
+// init reads and fingerprints the embedded assets. Files in subdirectories,
+// the images of vendored style sheets, are served under their plain path
+// since the style sheets refer to them by relative URLs. This function will
+// panic if the assets cannot be read.
+func init() {
+	err := fs.WalkDir(assetFS, "assets", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := assetFS.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		name := strings.TrimPrefix(p, "assets/")
+		a := &asset{
+			name:        name,
+			contentType: mime.TypeByExtension(path.Ext(name)),
+			content:     content,
+			url:         URLPrefix + name,
+		}
+		if path.Dir(name) == "." {
+			a.url = URLPrefix + fingerprint(name, content)
+			a.fingerprinted = true
+			assets[a.name] = a
+		}
+		byURL[a.url] = a
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// fingerprint inserts the content hash into name before the extension.
+func fingerprint(name string, content []byte) string {
+	sum := sha256.Sum256(content)
+	ext := path.Ext(name)
+	return fmt.Sprintf("%s.%s%s", strings.TrimSuffix(name, ext), hex.EncodeToString(sum[:])[:12], ext)
+}
+
+// Path returns the fingerprinted URL of the named asset. It is used as a
+// template function, so an unknown name is an error that fails template
+// execution.
+func Path(name string) (string, error) {
+	a, ok := assets[name]
+	if !ok {
+		return "", fmt.Errorf("unknown asset %q", name)
+	}
+	return a.url, nil
+}
+
+// Handler serves fingerprinted assets with long-lived cache headers. Since
+// the URL changes with the content, cached copies never go stale. Assets
+// under their plain path are cached for a day.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		a, ok := byURL[req.URL.Path]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		if a.contentType != "" {
+			w.Header().Set("Content-Type", a.contentType)
+		}
+		if a.fingerprinted {
+			w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d, immutable", int(maxAge.Seconds())))
+		} else {
+			w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(plainMaxAge.Seconds())))
+		}
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		http.ServeContent(w, req, a.name, time.Time{}, bytes.NewReader(a.content))
+	})
+}
+
+// inlineScript matches script elements of a page.
+var inlineScript = regexp.MustCompile(`(?is)<script([^>]*)>(.*?)</script>`)
+
+// ContentSecurityPolicy returns the Content-Security-Policy of a rendered
+// page. Only assets of the site may be loaded, and only the inline scripts
+// of the page itself may run, allowed by their hashes.
+func ContentSecurityPolicy(page []byte) string {
+	var hashes []string
+	for _, m := range inlineScript.FindAllSubmatch(page, -1) {
+		if bytes.Contains(bytes.ToLower(m[1]), []byte("src=")) {
+			continue
+		}
+		sum := sha256.Sum256(m[2])
+		hashes = append(hashes, "'sha256-"+base64.StdEncoding.EncodeToString(sum[:])+"'")
+	}
+	scriptSrc := "script-src 'self'"
+	if len(hashes) > 0 {
+		scriptSrc += " " + strings.Join(hashes, " ")
+	}
+	return strings.Join([]string{
+		"default-src 'none'",
+		scriptSrc,
+		// Style attributes are used by the templates and the SVG charts.
+		"style-src 'self' 'unsafe-inline'",
+		"img-src 'self' data:",
+		"connect-src 'self'",
+		"form-action 'self'",
+		"base-uri 'self'",
+		"frame-ancestors 'none'",
+	}, "; ")
+}
@@ -0,0 +1,88 @@
+package static
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	url, err := Path("dw_logo.svg")
+	if err != nil {
+		t.Fatalf("Path error: %v", err)
+	}
+	if !strings.HasPrefix(url, URLPrefix+"dw_logo.") || !strings.HasSuffix(url, ".svg") || url == URLPrefix+"dw_logo.svg" {
+		t.Errorf("Path is not fingerprinted, got: %s", url)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+	}{
+		{name: "fingerprinted", path: url, wantStatus: http.StatusOK},
+		{name: "plain_name", path: URLPrefix + "dw_logo.svg", wantStatus: http.StatusNotFound},
+		{name: "stale_fingerprint", path: URLPrefix + "dw_logo.000000000000.svg", wantStatus: http.StatusNotFound},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		Handler().ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+		if w.Code != test.wantStatus {
+			t.Errorf("Status mismatch for test: %s, got: %d, want: %d", test.name, w.Code, test.wantStatus)
+		}
+		if test.wantStatus != http.StatusOK {
+			continue
+		}
+		if cc := w.Header().Get("Cache-Control"); !strings.Contains(cc, "immutable") {
+			t.Errorf("Cache-Control mismatch for test: %s, got: %s", test.name, cc)
+		}
+		if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "image/svg+xml") {
+			t.Errorf("Content-Type mismatch for test: %s, got: %s", test.name, ct)
+		}
+	}
+
+	if _, err := Path("jquery.js"); err == nil {
+		t.Errorf("Path accepted unknown asset")
+	}
+}
+
+// cssURL matches the relative URLs of a style sheet.
+var cssURL = regexp.MustCompile(`url\(["']?([^"')]+)["']?\)`)
+
+func TestStyleSheetImages(t *testing.T) {
+	for name, a := range assets {
+		if path.Ext(name) != ".css" {
+			continue
+		}
+		for _, m := range cssURL.FindAllSubmatch(a.content, -1) {
+			ref := string(m[1])
+			if strings.HasPrefix(ref, "data:") || strings.Contains(ref, "//") {
+				continue
+			}
+			if _, ok := byURL[URLPrefix+path.Clean(ref)]; !ok {
+				t.Errorf("style sheet %s refers to missing asset %s", name, ref)
+			}
+		}
+	}
+}
+
+func TestContentSecurityPolicy(t *testing.T) {
+	inline := "\n  $(document).ready(function() {});\n"
+	page := `<script type="text/javascript" src="/static/jquery.js"></script>` +
+		`<script type="text/javascript">` + inline + `</script>`
+	sum := sha256.Sum256([]byte(inline))
+	want := "script-src 'self' 'sha256-" + base64.StdEncoding.EncodeToString(sum[:]) + "';"
+
+	csp := ContentSecurityPolicy([]byte(page))
+	if !strings.Contains(csp, want) {
+		t.Errorf("CSP mismatch, got: %s, want: %s", csp, want)
+	}
+	if csp := ContentSecurityPolicy([]byte("<p>no scripts</p>")); !strings.Contains(csp, "script-src 'self';") {
+		t.Errorf("CSP mismatch without inline scripts, got: %s", csp)
+	}
+}
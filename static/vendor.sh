@@ -0,0 +1,32 @@
+#!/bin/bash
+# Fetches the vendored front-end libraries into static/assets, at the versions
+# and from the hosts the pages loaded them from before they were self-hosted.
+# Run it from the repository root and commit the files it writes.
+set -euo pipefail
+
+dir=static/assets
+mkdir -p $dir/images
+
+fetch() {
+  curl -fsSL -o "$dir/$1" "$2"
+  sha256sum "$dir/$1"
+}
+
+# images fetches the images the style sheet refers to relative to base, and
+# rewrites the references to the images directory next to the style sheet.
+images() {
+  local css=$dir/$1 base=$2
+  for img in $(grep -o 'url([^)]*images/[^)]*)' "$css" | sed -e 's/^url(["'\'']\{0,1\}//' -e 's/["'\'']\{0,1\})$//' | sort -u); do
+    fetch "images/$(basename "$img")" "$base/$img"
+  done
+  sed -i -e 's#url(\(["'\'']\{0,1\}\)\(\.\./\)*images/#url(\1images/#g' "$css"
+}
+
+fetch jquery-1.10.2.min.js https://ajax.googleapis.com/ajax/libs/jquery/1.10.2/jquery.min.js
+fetch jquery-ui-1.10.3.min.js https://ajax.googleapis.com/ajax/libs/jqueryui/1.10.3/jquery-ui.min.js
+fetch jquery-ui-1.10.3.smoothness.css https://ajax.googleapis.com/ajax/libs/jqueryui/1.10.3/themes/smoothness/jquery-ui.css
+images jquery-ui-1.10.3.smoothness.css https://ajax.googleapis.com/ajax/libs/jqueryui/1.10.3/themes/smoothness
+fetch jquery.dataTables.js https://www.gstatic.com/external_hosted/jquery_datatables/js/jquery.dataTables.js
+fetch jquery.dataTables.css https://www.gstatic.com/external_hosted/jquery_datatables/css/jquery.dataTables.css
+images jquery.dataTables.css https://www.gstatic.com/external_hosted/jquery_datatables/css
+fetch maia.css https://www.google.com/css/maia.css